@@ -3,25 +3,30 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/labstack/echo/v4"
 	echomw "github.com/labstack/echo/v4/middleware"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/fx"
 	"golang.org/x/time/rate"
 
+	"vulners-proxy-go/internal/audit"
 	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
 	"vulners-proxy-go/internal/handler"
+	"vulners-proxy-go/internal/logfile"
 	"vulners-proxy-go/internal/metrics"
 	"vulners-proxy-go/internal/middleware"
+	"vulners-proxy-go/internal/routes"
 	"vulners-proxy-go/internal/service"
 )
 
@@ -46,18 +51,26 @@ func main() {
 			func() handler.Version { return handler.Version(version) },
 			config.Load,
 			newLogger,
+			newAuditLogger,
 			newMetrics,
+			newClientTracker,
+			newRateLimitStore,
+			newInFlightRegistry,
 			newEcho,
 			client.NewVulnersClient,
 			service.NewProxyService,
 			handler.NewProxyHandler,
 			handler.NewHealthHandler,
+			handler.NewMaintenanceHandler,
+			handler.NewMetricsJSONHandler,
+			handler.NewRateLimitHandler,
+			handler.NewInFlightHandler,
 		),
-		fx.Invoke(handler.RegisterRoutes, warnConfigPermissions, startServer),
+		fx.Invoke(handler.RegisterRoutes, setMetricPathPrefixes, warnConfig, runSelfTest, reloadOnSIGHUP, startHeartbeat, startServer),
 	).Run()
 }
 
-func newLogger(cfg *config.Config) *slog.Logger {
+func newLogger(lc fx.Lifecycle, cfg *config.Config) (*slog.Logger, error) {
 	level := slog.LevelInfo
 	switch strings.ToLower(cfg.Log.Level) {
 	case "debug":
@@ -70,84 +83,355 @@ func newLogger(cfg *config.Config) *slog.Logger {
 
 	opts := &slog.HandlerOptions{Level: level}
 
+	var w io.Writer = os.Stdout
+	switch cfg.Log.Output {
+	case "stderr":
+		w = os.Stderr
+	case "file":
+		f, err := logfile.Open(cfg.Log.FilePath, cfg.Log.MaxSizeMB, cfg.Log.MaxBackups, cfg.Log.MaxAgeDays)
+		if err != nil {
+			return nil, err
+		}
+		lc.Append(fx.Hook{
+			OnStop: func(_ context.Context) error { return f.Close() },
+		})
+		w = f
+	}
+
 	var h slog.Handler
 	switch strings.ToLower(cfg.Log.Format) {
 	case "text":
-		h = slog.NewTextHandler(os.Stdout, opts)
+		h = slog.NewTextHandler(w, opts)
 	default:
-		h = slog.NewJSONHandler(os.Stdout, opts)
+		h = slog.NewJSONHandler(w, opts)
 	}
 
-	return slog.New(h)
+	return slog.New(h), nil
+}
+
+func newAuditLogger(lc fx.Lifecycle, cfg *config.Config, logger *slog.Logger) (*audit.Logger, error) {
+	a, err := audit.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if a != nil {
+		logger.Info("audit log enabled", "output", cfg.Log.AuditOutput)
+		lc.Append(fx.Hook{
+			OnStop: func(_ context.Context) error { return a.Close() },
+		})
+	}
+	return a, nil
 }
 
-func newMetrics(cfg *config.Config) *metrics.Metrics {
+// newMetrics returns nil when metrics are disabled. A registration failure
+// disables metrics rather than stopping the proxy.
+func newMetrics(cfg *config.Config, logger *slog.Logger) *metrics.Metrics {
 	if !cfg.Metrics.Enabled {
 		return nil
 	}
-	return metrics.New()
+	m, err := metrics.NewWithError()
+	if err != nil {
+		logger.Error("metrics disabled: registration failed", "err", err)
+		return nil
+	}
+	return m
+}
+
+// Client activity reporting: distinct IPs are counted over activeClientsWindow
+// and the heaviest activeClientsTopN are logged every activeClientsInterval.
+const (
+	activeClientsWindow   = 5 * time.Minute
+	activeClientsInterval = time.Minute
+	activeClientsTopN     = 10
+)
+
+// newClientTracker tracks client IPs for the active_client_ips gauge and a
+// periodic log of the heaviest clients. It returns nil when metrics are
+// disabled.
+func newClientTracker(lc fx.Lifecycle, m *metrics.Metrics, logger *slog.Logger) *middleware.ClientTracker {
+	if m == nil {
+		return nil
+	}
+	t := middleware.NewClientTracker(activeClientsWindow, activeClientsInterval)
+	logger = logger.With("component", "client_tracker")
+
+	stop := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			go func() {
+				ticker := time.NewTicker(activeClientsInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						distinct, top := t.Snapshot(activeClientsTopN)
+						m.ActiveClientIPs.Set(float64(distinct))
+						if len(top) > 0 {
+							logger.Info("heaviest clients", "window", activeClientsWindow.String(),
+								"distinct_ips", distinct, "top", top)
+						}
+					case <-stop:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			close(stop)
+			return nil
+		},
+	})
+	return t
+}
+
+// newRateLimitStore returns the per-second rate limit store, adjustable at
+// runtime through PUT /proxy/ratelimit, or nil when rate limiting is off.
+func newRateLimitStore(cfg *config.Config) *middleware.AdjustableStore {
+	if !cfg.Server.RateLimit.Enabled {
+		return nil
+	}
+	return middleware.NewAdjustableStore(cfg.Server.RateLimit.RequestsPerSecond, func(rps float64) echomw.RateLimiterStore {
+		if cfg.Server.RateLimit.Algorithm == "sliding_window" {
+			return middleware.NewSlidingWindowStore(rps)
+		}
+		return echomw.NewRateLimiterMemoryStore(rate.Limit(rps))
+	})
 }
 
-func newEcho(cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) *echo.Echo {
+// newInFlightRegistry returns the registry behind GET /proxy/inflight, or nil
+// when no admin token is set to guard it.
+func newInFlightRegistry(cfg *config.Config) *middleware.InFlightRegistry {
+	if cfg.AdminToken() == "" {
+		return nil
+	}
+	return middleware.NewInFlightRegistry()
+}
+
+func newEcho(cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, m *metrics.Metrics, clients *middleware.ClientTracker, rateLimit *middleware.AdjustableStore, inFlight *middleware.InFlightRegistry, version handler.Version) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
+	e.HTTPErrorHandler = handler.HTTPErrorHandler(logger)
 
 	// Inbound timeouts to mitigate slow-client attacks.
-	e.Server.ReadTimeout = 30 * time.Second
+	e.Server.ReadTimeout = cfg.Server.ReadTimeout.Std()
 	// WriteTimeout is disabled (0) to avoid cutting off valid long-running streamed
-	// responses. Protection is provided by the upstream client timeout, ReadTimeout,
-	// and IdleTimeout.
+	// responses. Protection is provided by the upstream time-to-first-byte timeout,
+	// ReadTimeout, and IdleTimeout.
 	e.Server.WriteTimeout = 0
-	e.Server.IdleTimeout = 120 * time.Second
-	e.Server.ReadHeaderTimeout = 10 * time.Second
+	e.Server.IdleTimeout = cfg.Server.IdleTimeout.Std()
+	e.Server.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout.Std()
+
+	if cfg.Server.EnableH2C {
+		// Multiplexed streams share one connection, so a slow streamed
+		// download no longer ties up a whole connection; flow control is
+		// per stream. HTTP/1.1 remains available for other clients.
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		e.Server.Protocols = &protocols
+		logger.Info("cleartext HTTP/2 (h2c) enabled")
+	}
+
+	// Oversized paths and query strings are rejected before routing.
+	e.Pre(middleware.MaxPathLength(cfg.Server.MaxPathLength))
+	e.Pre(middleware.MaxQueryParams(cfg.Server.MaxQueryParams))
 
 	e.Use(echomw.Recover())
-	e.Use(echomw.RequestID())
-	e.Use(middleware.RequestLogger(logger))
+	e.Use(middleware.RequestID(cfg.Server.CorrelationIDHeader))
+	if cfg.Server.VersionExposed() {
+		e.Use(middleware.ResponseHeader("X-Proxy-Version", string(version)))
+	}
+	if cfg.Server.Environment != "" && cfg.Server.ExposeEnvironment {
+		e.Use(middleware.ResponseHeader("X-Environment", cfg.Server.Environment))
+	}
+	if inFlight != nil {
+		e.Use(inFlight.Middleware())
+	}
+	e.Use(middleware.RequestLogger(logger, cfg.Log.UseRouteTemplate, middleware.LogSampling{
+		Rate:          cfg.Log.RequestSampleRate(),
+		SlowThreshold: cfg.Upstream.SlowThreshold.Std(),
+	}))
 	if m != nil {
 		e.Use(middleware.MetricsMiddleware(m))
 	}
-	e.Use(echomw.BodyLimit(fmt.Sprintf("%dB", cfg.Server.BodyMaxBytes)))
-	e.Use(middleware.SecurityHeaders())
+	if n := cfg.Server.MaxInFlight; n > 0 {
+		e.Use(middleware.LoadShed(n, cfg.Server.ShedRetryAfter.Std(), []string{routes.APIv3, routes.APIv4}, m))
+		logger.Info("load shedding enabled", "max_in_flight", n)
+	}
+	if clients != nil {
+		e.Use(clients.Middleware())
+	}
+	bodyLimits := make([]middleware.BodyLimitRule, 0, len(cfg.Server.BodyLimits))
+	for _, bl := range cfg.Server.BodyLimits {
+		bodyLimits = append(bodyLimits, middleware.BodyLimitRule{
+			Methods:    bl.Methods,
+			PathPrefix: bl.PathPrefix,
+			MaxBytes:   bl.MaxBytes,
+		})
+	}
+	e.Use(middleware.BodyLimit(cfg.Server.BodyMaxBytes, bodyLimits))
+	e.Use(middleware.RejectUpgrades())
+	e.Use(middleware.SecurityHeaders(cfg.Server.SecurityHeaders.Headers()))
 
-	if cfg.Server.RateLimit.Enabled {
-		store := echomw.NewRateLimiterMemoryStore(rate.Limit(cfg.Server.RateLimit.RequestsPerSecond))
+	if rateLimit != nil {
+		var store echomw.RateLimiterStore = rateLimit
+		if rpm := cfg.Server.RateLimit.RequestsPerMinute; rpm > 0 {
+			store = middleware.AllStores{store, middleware.NewMinuteWindowStore(rpm)}
+		}
 		e.Use(echomw.RateLimiterWithConfig(echomw.RateLimiterConfig{
-			Store: store,
+			Skipper: middleware.PathPrefixSkipper(cfg.Server.RateLimit.ExemptPaths),
+			Store:   store,
 			IdentifierExtractor: func(c echo.Context) (string, error) {
 				// Use the direct TCP peer address, not X-Forwarded-For or
 				// X-Real-IP, to prevent rate-limit bypass via spoofed headers.
 				// If the proxy sits behind a trusted load balancer, configure
 				// Echo's TrustProxy settings and switch to c.RealIP() instead.
-				ip, _, err := net.SplitHostPort(c.Request().RemoteAddr)
-				if err != nil {
-					// RemoteAddr may lack a port (e.g. Unix socket); use it as-is.
-					return c.Request().RemoteAddr, nil //nolint:nilerr // fallback is intentional
-				}
-				return ip, nil
+				return middleware.PeerIP(c.Request()), nil
+			},
+			DenyHandler: func(c echo.Context, _ string, _ error) error {
+				auditLog.Record(c, audit.EventRateLimited)
+				return echomw.ErrRateLimitExceeded
 			},
 		}))
-		logger.Info("rate limiter enabled", "rps", cfg.Server.RateLimit.RequestsPerSecond)
+		logger.Info("rate limiter enabled",
+			"rps", cfg.Server.RateLimit.RequestsPerSecond,
+			"algorithm", cfg.Server.RateLimit.Algorithm,
+			"rpm", cfg.Server.RateLimit.RequestsPerMinute,
+			"exempt_paths", cfg.Server.RateLimit.ExemptPaths,
+		)
 	}
 
 	if m != nil {
-		e.GET(cfg.Metrics.Path, echo.WrapHandler(promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})))
+		e.GET(cfg.Metrics.Path, echo.WrapHandler(m.Handler()))
 		logger.Info("metrics endpoint enabled", "path", cfg.Metrics.Path)
 	}
 
 	return e
 }
 
-func warnConfigPermissions(cfg *config.Config, logger *slog.Logger) {
+// setMetricPathPrefixes aligns the path_prefix metric label with the routes
+// actually registered on the server, so new routes are not bucketed as "other".
+func setMetricPathPrefixes(e *echo.Echo, m *metrics.Metrics, logger *slog.Logger) {
+	if m == nil {
+		return
+	}
+	var prefixes []string
+	for _, r := range e.Routes() {
+		p := strings.TrimSuffix(r.Path, "/*")
+		if strings.ContainsAny(p, ":*") {
+			continue // parameterized routes would produce unbounded labels
+		}
+		prefixes = append(prefixes, p)
+	}
+	if dropped := m.SetPathPrefixes(prefixes); dropped > 0 {
+		logger.Warn("too many routes for metric path labels; extra routes are reported as \"other\"",
+			"max", metrics.MaxPathPrefixes,
+			"dropped", dropped,
+		)
+	}
+}
+
+func warnConfig(cfg *config.Config, logger *slog.Logger) {
 	cfg.WarnPermissions(logger)
+	cfg.WarnTimeouts(logger)
 }
 
-func startServer(lc fx.Lifecycle, e *echo.Echo, cfg *config.Config, logger *slog.Logger) {
+// runSelfTest checks upstream connectivity once at startup, before the
+// listener is opened, so misconfiguration surfaces at deploy time.
+func runSelfTest(lc fx.Lifecycle, cfg *config.Config, svc *service.ProxyService, logger *slog.Logger) {
+	if !cfg.Startup.SelfTest {
+		return
+	}
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, cfg.Startup.SelfTestTimeout.Std())
+			defer cancel()
+
+			err := svc.SelfTest(ctx, cfg.Startup.SelfTestPath)
+			switch {
+			case err == nil:
+				logger.Info("startup self-test passed", "path", cfg.Startup.SelfTestPath)
+			case cfg.Startup.FailOnSelfTest:
+				return fmt.Errorf("startup self-test: %w", err)
+			default:
+				logger.Error("startup self-test failed; starting anyway (startup.fail_on_self_test = false)", "err", err)
+			}
+			return nil
+		},
+	})
+}
+
+// reloadOnSIGHUP re-reads the config file on SIGHUP and applies the settings
+// that can change at runtime, currently maintenance.enabled. Everything else
+// still requires a restart.
+func reloadOnSIGHUP(lc fx.Lifecycle, cli *config.CLI, maint *handler.MaintenanceHandler, logger *slog.Logger) {
+	sig := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
 	lc.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
+			signal.Notify(sig, syscall.SIGHUP)
+			go func() {
+				for {
+					select {
+					case <-sig:
+						cfg, err := config.Load(cli)
+						if err != nil {
+							logger.Error("config reload failed; keeping current settings", "err", err)
+							continue
+						}
+						maint.SetEnabled(cfg.Maintenance.Enabled)
+						logger.Info("config reloaded", "maintenance", cfg.Maintenance.Enabled)
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			signal.Stop(sig)
+			close(done)
+			return nil
+		},
+	})
+}
+
+// startHeartbeat runs the liveness heartbeat checked by /healthz when
+// liveness.enabled is set.
+func startHeartbeat(lc fx.Lifecycle, cfg *config.Config, health *handler.HealthHandler, logger *slog.Logger) {
+	if !cfg.Liveness.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	lc.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			go health.RunHeartbeat(ctx)
+			logger.Info("liveness heartbeat enabled",
+				"interval", cfg.Liveness.HeartbeatInterval.Std().String(),
+				"stall_threshold", cfg.Liveness.StallThreshold.Std().String())
+			return nil
+		},
+		OnStop: func(_ context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+func startServer(lc fx.Lifecycle, e *echo.Echo, cfg *config.Config, proxy *handler.ProxyHandler, logger *slog.Logger) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
 			addr := cfg.Server.Addr()
-			ln, err := net.Listen("tcp", addr)
+			// ListenConfig enables keep-alive probes on every accepted
+			// connection; a negative period turns them off.
+			listenCfg := net.ListenConfig{KeepAlive: cfg.Server.TCPKeepAlive.Std()}
+			if cfg.Server.DisableTCPKeepAlive {
+				listenCfg.KeepAlive = -1
+			}
+			ln, err := listenCfg.Listen(ctx, "tcp", addr)
 			if err != nil {
 				return fmt.Errorf("bind %s: %w", addr, err)
 			}
@@ -160,8 +444,15 @@ func startServer(lc fx.Lifecycle, e *echo.Echo, cfg *config.Config, logger *slog
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
-			logger.Info("shutting down server")
-			return e.Shutdown(ctx)
+			logger.Info("shutting down server", "active_streams", proxy.ActiveStreams())
+			// Shutdown stops accepting connections and waits for in-flight
+			// requests, including streamed downloads, until ctx expires.
+			err := e.Shutdown(ctx)
+			if n := proxy.WaitStreams(ctx); n > 0 {
+				logger.Warn("shutdown deadline reached; closing active streams", "active_streams", n)
+				_ = e.Close()
+			}
+			return err
 		},
 	})
 }
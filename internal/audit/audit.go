@@ -0,0 +1,109 @@
+// Package audit writes security-relevant events to a dedicated log stream,
+// kept separate from the application and access logs so it can be shipped to
+// a SIEM on its own.
+package audit
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/config"
+)
+
+// Event types. These values are part of the audit feed's contract; do not
+// rename them.
+const (
+	EventAuthMissingKey = "auth_missing_key" // no API key in config or X-Api-Key
+	EventAuthRejected   = "auth_rejected"    // upstream answered 401 or 403
+	EventRateLimited    = "rate_limited"     // per-IP rate limit exceeded
+	EventAdminDenied    = "admin_denied"     // admin endpoint called without a valid token
+	EventMaintenance    = "maintenance_set"  // maintenance mode changed via the admin endpoint
+	EventRateLimitSet   = "rate_limit_set"   // rate limit changed via the admin endpoint
+)
+
+// Logger records audit events. A nil *Logger discards all events, so callers
+// do not need to check whether auditing is enabled.
+type Logger struct {
+	logger *slog.Logger
+	closer io.Closer
+}
+
+// New returns a Logger that writes events to w in the given format
+// ("json" or "text").
+func New(w io.Writer, format string) *Logger {
+	var h slog.Handler
+	if strings.ToLower(format) == "text" {
+		h = slog.NewTextHandler(w, nil)
+	} else {
+		h = slog.NewJSONHandler(w, nil)
+	}
+	return &Logger{logger: slog.New(h).With("log", "audit")}
+}
+
+// Open creates the audit Logger described by cfg.Log. It returns nil when
+// auditing is disabled.
+func Open(cfg *config.Config) (*Logger, error) {
+	if !cfg.Log.AuditEnabled {
+		return nil, nil
+	}
+
+	switch cfg.Log.AuditOutput {
+	case "stdout":
+		return New(os.Stdout, cfg.Log.Format), nil
+	case "stderr":
+		return New(os.Stderr, cfg.Log.Format), nil
+	}
+
+	f, err := os.OpenFile(cfg.Log.AuditOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", cfg.Log.AuditOutput, err)
+	}
+	l := New(f, cfg.Log.Format)
+	l.closer = f
+	return l, nil
+}
+
+// Close releases the underlying file, if any.
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Record writes an event for the request in c. Every event carries the event
+// type, client IP, request ID, method, and path; attrs add event-specific
+// fields. Query strings and headers are never recorded, so API keys cannot
+// leak into the audit stream.
+func (l *Logger) Record(c echo.Context, event string, attrs ...any) {
+	if l == nil {
+		return
+	}
+	req := c.Request()
+	l.logger.Warn("audit event",
+		append([]any{
+			"event", event,
+			"client_ip", clientIP(req.RemoteAddr),
+			"request_id", c.Response().Header().Get(echo.HeaderXRequestID),
+			"method", req.Method,
+			"path", req.URL.Path,
+		}, attrs...)...,
+	)
+}
+
+// clientIP returns the direct TCP peer address, matching the identifier used
+// by the rate limiter. Forwarding headers are ignored because they can be
+// spoofed.
+func clientIP(remoteAddr string) string {
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return ip
+}
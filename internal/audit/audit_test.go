@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/config"
+)
+
+func TestRecord(t *testing.T) {
+	var buf strings.Builder
+	l := New(&buf, "json")
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=x&apiKey=secret", http.NoBody)
+	req.RemoteAddr = "192.0.2.10:54321"
+	req.Header.Set("X-Api-Key", "secret")
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	rec := httptest.NewRecorder()
+	rec.Header().Set(echo.HeaderXRequestID, "req-42")
+	c := e.NewContext(req, rec)
+
+	l.Record(c, EventRateLimited, "status", 429)
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := map[string]any{
+		"log":        "audit",
+		"event":      EventRateLimited,
+		"client_ip":  "192.0.2.10",
+		"request_id": "req-42",
+		"method":     http.MethodGet,
+		"path":       "/api/v3/search/lucene/",
+		"status":     float64(429),
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Error("audit entry must not contain the API key")
+	}
+}
+
+func TestRecord_NilLogger(t *testing.T) {
+	var l *Logger
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", http.NoBody), httptest.NewRecorder())
+	l.Record(c, EventAuthMissingKey) // must not panic
+	if err := l.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		l, err := Open(&config.Config{})
+		if err != nil || l != nil {
+			t.Errorf("Open() = %v, %v; want nil, nil", l, err)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		cfg := &config.Config{Log: config.LogConfig{AuditEnabled: true, AuditOutput: path, Format: "json"}}
+		l, err := Open(cfg)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/v3/x", http.NoBody), httptest.NewRecorder())
+		l.Record(c, EventAuthMissingKey)
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("mode = %04o, want 0600", perm)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), EventAuthMissingKey) {
+			t.Errorf("audit file = %q, want %q event", data, EventAuthMissingKey)
+		}
+	})
+
+	t.Run("unwritable path", func(t *testing.T) {
+		cfg := &config.Config{Log: config.LogConfig{AuditEnabled: true, AuditOutput: filepath.Join(t.TempDir(), "missing", "audit.log")}}
+		if _, err := Open(cfg); err == nil {
+			t.Error("Open() error = nil, want error")
+		}
+	})
+}
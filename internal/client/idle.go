@@ -0,0 +1,71 @@
+package client
+
+import (
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// idleTracker approximates the number of idle upstream connections, which
+// http.Transport does not expose. It watches connections being returned to
+// and taken from the pool through httptrace. Connections the transport drops
+// on its own are not reported, so an entry is assumed closed once it has
+// been idle for the transport's idle timeout; one closed early by the server
+// is still counted until then.
+type idleTracker struct {
+	mu      sync.Mutex
+	idle    map[net.Conn]time.Time // connection → when it was returned to the pool
+	timeout time.Duration
+	gauge   prometheus.Gauge
+
+	now func() time.Time
+}
+
+func newIdleTracker(timeout time.Duration, gauge prometheus.Gauge) *idleTracker {
+	return &idleTracker{
+		idle:    make(map[net.Conn]time.Time),
+		timeout: timeout,
+		gauge:   gauge,
+		now:     time.Now,
+	}
+}
+
+// trace returns a ClientTrace for one request. The trace remembers the
+// request's connection so that returning it to the pool can be attributed.
+func (t *idleTracker) trace() *httptrace.ClientTrace {
+	var conn net.Conn
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn = info.Conn
+			if info.WasIdle {
+				t.update(func() { delete(t.idle, conn) })
+			}
+		},
+		PutIdleConn: func(err error) {
+			if err == nil && conn != nil {
+				t.update(func() { t.idle[conn] = t.now() })
+			}
+		},
+	}
+}
+
+// update applies change, drops entries the transport will have closed, and
+// refreshes the gauge.
+func (t *idleTracker) update(change func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	change()
+	if t.timeout > 0 {
+		cutoff := t.now().Add(-t.timeout)
+		for c, since := range t.idle {
+			if since.Before(cutoff) {
+				delete(t.idle, c)
+			}
+		}
+	}
+	t.gauge.Set(float64(len(t.idle)))
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+func TestIdleTracker(t *testing.T) {
+	m := metrics.New()
+	clock := time.Unix(1_700_000_000, 0)
+	tr := newIdleTracker(90*time.Second, m.UpstreamIdleConns)
+	tr.now = func() time.Time { return clock }
+
+	a, _ := net.Pipe()
+	b, _ := net.Pipe()
+
+	// Two requests on fresh connections, both returned to the pool.
+	for _, conn := range []net.Conn{a, b} {
+		trace := tr.trace()
+		trace.GotConn(httptrace.GotConnInfo{Conn: conn})
+		trace.PutIdleConn(nil)
+	}
+	if got := gatherValue(t, m, "vulners_proxy_upstream_idle_conns"); got != 2 {
+		t.Fatalf("idle after two returns = %v, want 2", got)
+	}
+
+	// A request reusing an idle connection takes it out of the pool.
+	trace := tr.trace()
+	trace.GotConn(httptrace.GotConnInfo{Conn: a, Reused: true, WasIdle: true})
+	if got := gatherValue(t, m, "vulners_proxy_upstream_idle_conns"); got != 1 {
+		t.Errorf("idle while reused = %v, want 1", got)
+	}
+
+	// A connection that is not kept (e.g. pool full) is not counted.
+	trace.PutIdleConn(http.ErrServerClosed)
+	if got := gatherValue(t, m, "vulners_proxy_upstream_idle_conns"); got != 1 {
+		t.Errorf("idle after rejected return = %v, want 1", got)
+	}
+
+	// Past the idle timeout the transport has closed b.
+	clock = clock.Add(2 * time.Minute)
+	c, _ := net.Pipe()
+	trace = tr.trace()
+	trace.GotConn(httptrace.GotConnInfo{Conn: c})
+	trace.PutIdleConn(nil)
+	if got := gatherValue(t, m, "vulners_proxy_upstream_idle_conns"); got != 1 {
+		t.Errorf("idle after expiry = %v, want 1", got)
+	}
+}
+
+func TestVulnersClient_IdleConnsGauge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{IdleConnections: 10},
+	}
+	m := metrics.New()
+	c := NewVulnersClient(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), m)
+
+	for range 3 {
+		resp, err := c.DoStream(context.Background(), http.MethodGet, srv.URL, http.Header{}, nil)
+		if err != nil {
+			t.Fatalf("DoStream() error = %v", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	// Sequential requests share one keep-alive connection.
+	if got := gatherValue(t, m, "vulners_proxy_upstream_idle_conns"); got != 1 {
+		t.Errorf("idle conns = %v, want 1", got)
+	}
+}
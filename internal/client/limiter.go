@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"vulners-proxy-go/internal/metrics"
+)
+
+// ErrQueueFull is returned when all upstream slots are busy and the wait queue
+// has no room for another request.
+var ErrQueueFull = errors.New("upstream concurrency limit reached and wait queue is full")
+
+// ErrQueueTimeout is returned when a request waited in the queue for longer
+// than the configured queue timeout without obtaining an upstream slot.
+var ErrQueueTimeout = errors.New("timed out waiting for a free upstream slot")
+
+// concurrencyLimiter caps the number of in-flight upstream requests. Requests
+// that arrive while all slots are busy wait in a bounded queue for up to
+// queueTimeout. Blocked senders on a Go channel are served in arrival order,
+// so the queue is FIFO.
+type concurrencyLimiter struct {
+	slots        chan struct{}
+	maxQueue     int64
+	queueTimeout time.Duration
+	queued       atomic.Int64
+	metrics      *metrics.Metrics
+}
+
+func newConcurrencyLimiter(maxConcurrent, maxQueue int, queueTimeout time.Duration, m *metrics.Metrics) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		slots:        make(chan struct{}, maxConcurrent),
+		maxQueue:     int64(maxQueue),
+		queueTimeout: queueTimeout,
+		metrics:      m,
+	}
+}
+
+// acquire obtains an upstream slot and returns a function that releases it.
+// It returns ErrQueueFull, ErrQueueTimeout, or the context error when no slot
+// could be obtained. The time spent waiting is recorded by outcome.
+func (l *concurrencyLimiter) acquire(ctx context.Context) (func(), error) {
+	start := time.Now()
+
+	select {
+	case l.slots <- struct{}{}:
+		l.observeWait(start, "admitted")
+		return l.release, nil
+	default:
+	}
+
+	if l.queued.Add(1) > l.maxQueue {
+		l.queued.Add(-1)
+		l.observeWait(start, "queue_full")
+		return nil, ErrQueueFull
+	}
+	if l.metrics != nil {
+		l.metrics.QueueDepth.Inc()
+	}
+	defer func() {
+		l.queued.Add(-1)
+		if l.metrics != nil {
+			l.metrics.QueueDepth.Dec()
+		}
+	}()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		l.observeWait(start, "admitted")
+		return l.release, nil
+	case <-timer.C:
+		l.observeWait(start, "timeout")
+		if l.metrics != nil {
+			l.metrics.QueueTimeouts.Inc()
+		}
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		l.observeWait(start, "canceled")
+		return nil, ctx.Err()
+	}
+}
+
+func (l *concurrencyLimiter) observeWait(start time.Time, outcome string) {
+	if l.metrics != nil {
+		l.metrics.AdmissionWait.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+}
+
+// releaseOnClose wraps a response body so that the upstream slot is held
+// until the caller has finished streaming and closes the body.
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"vulners-proxy-go/internal/metrics"
+)
+
+func TestConcurrencyLimiter_AcquireRelease(t *testing.T) {
+	l := newConcurrencyLimiter(1, 0, time.Second, nil)
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Slot is busy and there is no queue: reject immediately.
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("acquire() error = %v, want ErrQueueFull", err)
+	}
+
+	release()
+
+	release, err = l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	release()
+}
+
+func TestConcurrencyLimiter_QueueTimeout(t *testing.T) {
+	m := metrics.New()
+	l := newConcurrencyLimiter(1, 1, 20*time.Millisecond, m)
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	if _, err := l.acquire(context.Background()); !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("acquire() error = %v, want ErrQueueTimeout", err)
+	}
+	if v := gatherValue(t, m, "vulners_proxy_queue_timeouts_total"); v != 1 {
+		t.Errorf("queue timeouts = %v, want 1", v)
+	}
+	if v := gatherValue(t, m, "vulners_proxy_queue_depth"); v != 0 {
+		t.Errorf("queue depth = %v, want 0", v)
+	}
+}
+
+func TestConcurrencyLimiter_AdmissionWait(t *testing.T) {
+	m := metrics.New()
+	l := newConcurrencyLimiter(1, 0, time.Second, m)
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+	_, _ = l.acquire(context.Background())
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, f := range families {
+		if f.GetName() != "vulners_proxy_admission_wait_seconds" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			for _, lp := range metric.GetLabel() {
+				if lp.GetName() == "outcome" {
+					counts[lp.GetValue()] = metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	if counts["admitted"] != 1 || counts["queue_full"] != 1 {
+		t.Errorf("admission wait samples = %v, want admitted=1 queue_full=1", counts)
+	}
+}
+
+func TestConcurrencyLimiter_QueuedRequestGetsSlot(t *testing.T) {
+	l := newConcurrencyLimiter(1, 1, time.Second, nil)
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		r, err := l.acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	release()
+
+	if err := <-done; err != nil {
+		t.Errorf("queued acquire() error = %v", err)
+	}
+}
+
+func TestConcurrencyLimiter_ContextCanceled(t *testing.T) {
+	l := newConcurrencyLimiter(1, 1, time.Minute, nil)
+
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("acquire() error = %v, want context.Canceled", err)
+	}
+	if n := l.queued.Load(); n != 0 {
+		t.Errorf("queued = %d, want 0 after cancellation", n)
+	}
+}
+
+func TestReleaseOnClose_ReleasesOnce(t *testing.T) {
+	calls := 0
+	body := &releaseOnClose{
+		ReadCloser: io.NopCloser(strings.NewReader("x")),
+		release:    func() { calls++ },
+	}
+
+	_ = body.Close()
+	_ = body.Close()
+
+	if calls != 1 {
+		t.Errorf("release called %d times, want 1", calls)
+	}
+}
+
+// gatherValue returns the value of an unlabeled counter or gauge.
+func gatherValue(t *testing.T, m *metrics.Metrics, name string) float64 {
+	t.Helper()
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			if c := metric.GetCounter(); c != nil {
+				return c.GetValue()
+			}
+			return metric.GetGauge().GetValue()
+		}
+	}
+	t.Fatalf("metric %q not found", name)
+	return 0
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// successRateBuckets is the number of buckets the success rate window is
+// split into; the window slides forward one bucket at a time.
+const successRateBuckets = 12
+
+// successRate tracks the share of successful upstream calls over a sliding
+// window, in fixed time buckets. A call succeeds when upstream answers with
+// a 2xx or 3xx status; other statuses, timeouts and connection errors are
+// failures. Calls canceled by the client are not recorded.
+type successRate struct {
+	mu      sync.Mutex
+	bucket  time.Duration
+	buckets []outcomeBucket // ring; index is bucket start / bucket width mod len
+
+	now func() time.Time
+}
+
+type outcomeBucket struct {
+	start     time.Time
+	successes int
+	total     int
+}
+
+func newSuccessRate(window time.Duration) *successRate {
+	return &successRate{
+		bucket:  max(window/successRateBuckets, time.Millisecond),
+		buckets: make([]outcomeBucket, successRateBuckets),
+		now:     time.Now,
+	}
+}
+
+// record counts one upstream call.
+func (r *successRate) record(success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := r.now().Truncate(r.bucket)
+	b := &r.buckets[int(start.UnixNano()/int64(r.bucket))%len(r.buckets)]
+	if !b.start.Equal(start) {
+		*b = outcomeBucket{start: start}
+	}
+	b.total++
+	if success {
+		b.successes++
+	}
+}
+
+// ratio returns the share of successful calls within the window and the
+// number of calls it is based on. With no calls in the window it reports 1.
+func (r *successRate) ratio() (float64, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ratioLocked()
+}
+
+func (r *successRate) ratioLocked() (float64, int) {
+	var successes, total int
+	oldest := r.now().Truncate(r.bucket).Add(-time.Duration(len(r.buckets)-1) * r.bucket)
+	for _, b := range r.buckets {
+		if b.start.Before(oldest) {
+			continue
+		}
+		successes += b.successes
+		total += b.total
+	}
+	if total == 0 {
+		return 1, 0
+	}
+	return float64(successes) / float64(total), total
+}
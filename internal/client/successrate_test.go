@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+func TestSuccessRate(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	r := newSuccessRate(time.Minute)
+	r.now = func() time.Time { return clock }
+
+	if ratio, calls := r.ratio(); ratio != 1 || calls != 0 {
+		t.Errorf("empty window: ratio = %v over %d calls, want 1 over 0", ratio, calls)
+	}
+
+	// Three successes and one failure.
+	for _, ok := range []bool{true, true, true, false} {
+		r.record(ok)
+	}
+	if ratio, calls := r.ratio(); ratio != 0.75 || calls != 4 {
+		t.Errorf("ratio = %v over %d calls, want 0.75 over 4", ratio, calls)
+	}
+
+	// Half a window later, one more failure: all five calls still count.
+	clock = clock.Add(30 * time.Second)
+	r.record(false)
+	if ratio, calls := r.ratio(); ratio != 0.6 || calls != 5 {
+		t.Errorf("ratio = %v over %d calls, want 0.6 over 5", ratio, calls)
+	}
+
+	// Once the first four calls have left the window, only the last remains.
+	clock = clock.Add(40 * time.Second)
+	if ratio, calls := r.ratio(); ratio != 0 || calls != 1 {
+		t.Errorf("ratio = %v over %d calls, want 0 over 1", ratio, calls)
+	}
+	r.record(true)
+	if ratio, calls := r.ratio(); ratio != 0.5 || calls != 2 {
+		t.Errorf("ratio = %v over %d calls, want 0.5 over 2", ratio, calls)
+	}
+
+	// After a full window without calls the ratio resets.
+	clock = clock.Add(2 * time.Minute)
+	if ratio, calls := r.ratio(); ratio != 1 || calls != 0 {
+		t.Errorf("idle window: ratio = %v over %d calls, want 1 over 0", ratio, calls)
+	}
+}
+
+func TestVulnersClient_SuccessRatio(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/moved":
+			w.Header().Set("Location", "/ok")
+			w.WriteHeader(http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{Upstream: config.UpstreamConfig{
+		IdleConnections: 10,
+		SuccessWindow:   config.Duration(time.Minute),
+	}}
+	m := metrics.New()
+	c := NewVulnersClient(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), m)
+
+	do := func(ctx context.Context, path string) {
+		t.Helper()
+		resp, err := c.DoStream(ctx, http.MethodGet, upstream.URL+path, http.Header{}, http.NoBody)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}
+	do(context.Background(), "/ok")
+	do(context.Background(), "/moved")
+	do(context.Background(), "/fail")
+
+	// A call canceled by the client says nothing about upstream health.
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	do(canceled, "/ok")
+
+	// Nor do connectivity probes.
+	do(AsProbe(context.Background()), "/fail")
+	do(AsProbe(context.Background()), "/ok")
+
+	ratio, calls := c.SuccessRatio()
+	if calls != 3 || ratio < 0.66 || ratio > 0.67 {
+		t.Errorf("SuccessRatio() = %v over %d calls, want 2/3 over 3", ratio, calls)
+	}
+	if got := gatherValue(t, m, "vulners_proxy_upstream_success_ratio"); got != ratio {
+		t.Errorf("gauge = %v, want %v", got, ratio)
+	}
+
+	// The gauge is computed at scrape time, so it recovers once the
+	// failures leave the window even without further calls.
+	c.success.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if got := gatherValue(t, m, "vulners_proxy_upstream_success_ratio"); got != 1 {
+		t.Errorf("gauge after an idle window = %v, want 1", got)
+	}
+}
@@ -3,11 +3,14 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"time"
 
@@ -16,70 +19,240 @@ import (
 	"vulners-proxy-go/internal/model"
 )
 
+// retryKey marks a request context as a retry of an earlier upstream call.
+type retryKey struct{}
+
+// WithRetry returns a context that marks requests sent with it as retries,
+// so upstream response metrics count them apart from first attempts.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+// probeKey marks a request context as a connectivity probe.
+type probeKey struct{}
+
+// AsProbe returns a context that marks requests sent with it as connectivity
+// probes (the startup self-test, on-demand status checks). Probes are left
+// out of the upstream success ratio, so checking upstream does not change
+// the health signal derived from real traffic.
+func AsProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, probeKey{}, true)
+}
+
+func isProbe(ctx context.Context) bool {
+	probe, _ := ctx.Value(probeKey{}).(bool)
+	return probe
+}
+
+// attemptLabel returns the attempt metric label for a request context.
+func attemptLabel(ctx context.Context) string {
+	if retry, _ := ctx.Value(retryKey{}).(bool); retry {
+		return metrics.AttemptRetry
+	}
+	return metrics.AttemptFirst
+}
+
 // VulnersClient sends requests to the upstream Vulners API.
 type VulnersClient struct {
 	httpClient *http.Client
 	logger     *slog.Logger
 	metrics    *metrics.Metrics
+	limiter    *concurrencyLimiter // nil when upstream concurrency is unlimited
+	slow       time.Duration       // warn about calls slower than this; 0 disables
+	idle       *idleTracker        // nil when metrics are disabled
+	success    *successRate
 }
 
 // NewVulnersClient creates a VulnersClient with connection pooling and timeouts.
 // The metrics parameter is optional; pass nil to disable upstream metrics recording.
 func NewVulnersClient(cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) *VulnersClient {
+	// Jitter is drawn once per process so that replicas started together
+	// do not expire their idle connections in lockstep.
+	jitter := cfg.Upstream.IdleTimeoutJitterPercent
+	idleTimeout := jitterDuration(cfg.Upstream.IdleConnTimeout.Std(), jitter, rand.Float64)
+	keepAlive := jitterDuration(30*time.Second, jitter, rand.Float64)
+
 	transport := &http.Transport{
-		MaxIdleConns:        cfg.Upstream.IdleConnections,
-		MaxIdleConnsPerHost: cfg.Upstream.IdleConnections,
-		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:          cfg.Upstream.IdleConnections,
+		MaxIdleConnsPerHost:   cfg.Upstream.IdleConnections,
+		IdleConnTimeout:       idleTimeout,
+		ResponseHeaderTimeout: cfg.Upstream.ResponseHeaderTimeout.Std(),
+		DisableKeepAlives:     cfg.Upstream.DisableKeepAlive,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
+			KeepAlive: keepAlive,
 		}).DialContext,
 	}
 
-	return &VulnersClient{
+	// No overall client Timeout: it would also bound body streaming and cut
+	// off long downloads. Time-to-first-byte is bounded per request by the
+	// service and by the transport's ResponseHeaderTimeout.
+	logger = logger.With("component", "vulners_client")
+	if cfg.Upstream.DisableKeepAlive {
+		logger.Warn("upstream keep-alive disabled; every request opens a new connection (debug only)")
+	}
+	if jitter > 0 {
+		logger.Debug("upstream connection timeouts jittered",
+			"idle_timeout", idleTimeout,
+			"keep_alive", keepAlive,
+		)
+	}
+
+	vc := &VulnersClient{
 		httpClient: &http.Client{
 			Transport: transport,
-			Timeout:   time.Duration(cfg.Upstream.TimeoutSeconds) * time.Second,
+			// Redirects are returned to the service rather than followed,
+			// so the API key is never sent to a host chosen by upstream.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
 		},
-		logger:  logger.With("component", "vulners_client"),
+		logger:  logger,
 		metrics: m,
+		slow:    cfg.Upstream.SlowThreshold.Std(),
+	}
+	vc.success = newSuccessRate(cfg.Upstream.SuccessWindow.Std())
+	if m != nil {
+		vc.idle = newIdleTracker(idleTimeout, m.UpstreamIdleConns)
+		m.SetUpstreamSuccessRatio(func() float64 {
+			ratio, _ := vc.success.ratio()
+			return ratio
+		})
+	}
+	if cfg.Upstream.MaxConcurrentRequests > 0 {
+		vc.limiter = newConcurrencyLimiter(
+			cfg.Upstream.MaxConcurrentRequests,
+			cfg.Upstream.MaxQueuedRequests,
+			cfg.Upstream.QueueTimeout.Std(),
+			m,
+		)
 	}
+	return vc
+}
+
+// jitterDuration spreads d uniformly across ±percent of its value. rnd must
+// return a value in [0, 1).
+func jitterDuration(d time.Duration, percent int, rnd func() float64) time.Duration {
+	if percent <= 0 {
+		return d
+	}
+	spread := float64(d) * float64(percent) / 100
+	return d + time.Duration((rnd()*2-1)*spread)
 }
 
 // Do executes an HTTP request against the upstream and returns the raw response.
 // The caller is responsible for closing the response body.
+//
+// When an upstream concurrency limit is configured, Do first waits for a free
+// slot (see ErrQueueFull and ErrQueueTimeout). The slot is held until the
+// returned body is closed.
 func (c *VulnersClient) Do(req *http.Request) (*model.ProxyResponse, error) {
+	release := func() {}
+	if c.limiter != nil {
+		r, err := c.limiter.acquire(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("upstream slot: %w", err)
+		}
+		release = r
+	}
+
 	c.logger.Debug("upstream request",
 		"method", req.Method,
 		"path", req.URL.Path,
 	)
 
+	if c.idle != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), c.idle.trace()))
+	}
+
 	start := time.Now()
 	resp, err := c.httpClient.Do(req) //nolint:bodyclose // body ownership transfers to caller via ProxyResponse
-	duration := time.Since(start).Seconds()
+	elapsed := time.Since(start)
+	duration := elapsed.Seconds()
+	c.warnIfSlow(req, elapsed)
 
 	method := metrics.NormalizeMethod(req.Method)
 
 	if err != nil {
+		release()
+		timeout := isTimeout(req.Context(), err)
+		if !isProbe(req.Context()) && (timeout || !errors.Is(err, context.Canceled)) {
+			c.success.record(false)
+		}
 		if c.metrics != nil {
 			c.metrics.UpstreamDuration.WithLabelValues(method).Observe(duration)
+			switch {
+			case timeout:
+				c.metrics.UpstreamTimeouts.WithLabelValues(method).Inc()
+			case errors.Is(err, context.Canceled):
+				c.metrics.UpstreamCanceled.WithLabelValues(method).Inc()
+			}
 		}
 		return nil, fmt.Errorf("upstream request: %w", err)
 	}
 
+	if !isProbe(req.Context()) {
+		c.success.record(resp.StatusCode >= 200 && resp.StatusCode < 400)
+	}
+	status := strconv.Itoa(resp.StatusCode)
 	if c.metrics != nil {
-		status := strconv.Itoa(resp.StatusCode)
 		c.metrics.UpstreamDuration.WithLabelValues(method).Observe(duration)
-		c.metrics.UpstreamResponses.WithLabelValues(method, status).Inc()
+		c.metrics.UpstreamResponses.WithLabelValues(method, status, attemptLabel(req.Context())).Inc()
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		c.logger.Warn("upstream rejected API key; check that it is valid and not expired",
+			"status", resp.StatusCode,
+			"method", req.Method,
+			"path", req.URL.Path,
+		)
+		if c.metrics != nil {
+			c.metrics.UpstreamAuthFailures.WithLabelValues(status).Inc()
+		}
 	}
 
 	return &model.ProxyResponse{
 		StatusCode: resp.StatusCode,
 		Header:     resp.Header,
-		Body:       resp.Body,
+		Body:       &releaseOnClose{ReadCloser: resp.Body, release: release},
+		Duration:   elapsed,
 	}, nil
 }
 
+// SuccessRatio returns the share of upstream calls within
+// upstream.success_window that got a 2xx or 3xx response, and the number of
+// calls it is based on. Timeouts and connection errors count as failures;
+// calls canceled by the client are left out. With no calls in the window the
+// ratio is 1.
+func (c *VulnersClient) SuccessRatio() (ratio float64, calls int) {
+	return c.success.ratio()
+}
+
+// warnIfSlow logs upstream calls that exceeded the slow threshold. Only the
+// URL path is logged: the query string may carry an API key.
+func (c *VulnersClient) warnIfSlow(req *http.Request, elapsed time.Duration) {
+	if c.slow <= 0 || elapsed <= c.slow {
+		return
+	}
+	c.logger.Warn("upstream slow",
+		"method", req.Method,
+		"path", req.URL.Path,
+		"duration_ms", elapsed.Milliseconds(),
+		"threshold_ms", c.slow.Milliseconds(),
+	)
+}
+
+// isTimeout reports whether an upstream error was caused by the upstream being
+// too slow rather than by the client going away. The service bounds
+// time-to-first-byte by canceling the request context with
+// context.DeadlineExceeded as the cause, so the cause is checked as well.
+func isTimeout(ctx context.Context, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
 // DoStream executes a request and returns the response body as a stream.
 // The caller is responsible for closing the returned ReadCloser.
 // The provided context controls the lifetime of the upstream request:
@@ -91,6 +264,20 @@ func (c *VulnersClient) DoStream(ctx context.Context, method, url string, header
 		return nil, fmt.Errorf("build upstream request: %w", err)
 	}
 	req.Header = header
+	// net/http ignores a Host entry in Header for client requests; it has
+	// to be set on the request. The connection and TLS server name still
+	// use the URL's host.
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	// Likewise for Content-Length: without it a streamed body is sent
+	// chunked. Forwarding the client's length keeps large POSTs (often sent
+	// with Expect: 100-continue) framed the way the client sent them.
+	if req.ContentLength == 0 && body != nil && body != http.NoBody {
+		if n, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil && n > 0 {
+			req.ContentLength = n
+		}
+	}
 
 	return c.Do(req)
 }
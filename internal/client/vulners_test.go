@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,7 +24,7 @@ func TestVulnersClient_DoStream(t *testing.T) {
 
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -59,7 +60,7 @@ func TestVulnersClient_DoStream_WithMetrics(t *testing.T) {
 
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -98,7 +99,7 @@ func TestVulnersClient_DoStream_WithMetrics(t *testing.T) {
 				for _, lp := range metric.GetLabel() {
 					labels[lp.GetName()] = lp.GetValue()
 				}
-				if labels["method"] == "GET" && labels["status_code"] == "200" {
+				if labels["method"] == "GET" && labels["status_code"] == "200" && labels["attempt"] == "first" {
 					foundResponses = true
 					if v := metric.GetCounter().GetValue(); v != 1 {
 						t.Errorf("upstream_responses_total counter = %v, want 1", v)
@@ -111,14 +112,57 @@ func TestVulnersClient_DoStream_WithMetrics(t *testing.T) {
 		t.Error("expected upstream_request_duration_seconds with at least one sample")
 	}
 	if !foundResponses {
-		t.Error("expected upstream_responses_total with method=GET, status_code=200")
+		t.Error("expected upstream_responses_total with method=GET, status_code=200, attempt=first")
+	}
+}
+
+func TestVulnersClient_DoStream_RetryAttemptLabel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{IdleConnections: 10},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := metrics.New()
+	c := NewVulnersClient(cfg, logger, m)
+
+	for _, ctx := range []context.Context{context.Background(), WithRetry(context.Background()), WithRetry(context.Background())} {
+		resp, err := c.DoStream(ctx, http.MethodGet, srv.URL+"/test", http.Header{}, nil)
+		if err != nil {
+			t.Fatalf("DoStream() error = %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "vulners_proxy_upstream_responses_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			for _, lp := range metric.GetLabel() {
+				if lp.GetName() == "attempt" {
+					got[lp.GetValue()] += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if len(got) != 2 || got["first"] != 1 || got["retry"] != 2 {
+		t.Errorf("upstream_responses_total by attempt = %v, want first=1 retry=2", got)
 	}
 }
 
 func TestVulnersClient_DoStream_ErrorWithMetrics(t *testing.T) {
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{
-			TimeoutSeconds:  1,
+			Timeout:         config.Duration(1 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -155,7 +199,7 @@ func TestVulnersClient_DoStream_ErrorWithMetrics(t *testing.T) {
 func TestVulnersClient_DoStream_Error(t *testing.T) {
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{
-			TimeoutSeconds:  1,
+			Timeout:         config.Duration(1 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -178,7 +222,7 @@ func TestVulnersClient_DoStream_CanceledContext(t *testing.T) {
 
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{
-			TimeoutSeconds:  30,
+			Timeout:         config.Duration(30 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -193,3 +237,240 @@ func TestVulnersClient_DoStream_CanceledContext(t *testing.T) {
 		t.Fatal("DoStream() expected error for canceled context, got nil")
 	}
 }
+
+func TestNewVulnersClient_DisableKeepAlive(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, disable := range []bool{false, true} {
+		cfg := &config.Config{
+			Upstream: config.UpstreamConfig{
+				IdleConnections:  10,
+				DisableKeepAlive: disable,
+			},
+		}
+		c := NewVulnersClient(cfg, logger, nil)
+
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("Transport = %T, want *http.Transport", c.httpClient.Transport)
+		}
+		if transport.DisableKeepAlives != disable {
+			t.Errorf("DisableKeepAlives = %v, want %v", transport.DisableKeepAlives, disable)
+		}
+	}
+}
+
+func TestJitterDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent int
+		rnd     float64
+		want    time.Duration
+	}{
+		{"disabled", 0, 0, 90 * time.Second},
+		{"lower bound", 20, 0, 72 * time.Second},
+		{"midpoint", 20, 0.5, 90 * time.Second},
+		{"near upper bound", 20, 0.75, 99 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := jitterDuration(90*time.Second, tt.percent, func() float64 { return tt.rnd })
+			if got != tt.want {
+				t.Errorf("jitterDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVulnersClient_IdleConnTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{
+			IdleConnections: 10,
+			IdleConnTimeout: config.Duration(25 * time.Second),
+		},
+	}
+
+	c := NewVulnersClient(cfg, logger, nil)
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, want *http.Transport", c.httpClient.Transport)
+	}
+	if transport.IdleConnTimeout != 25*time.Second {
+		t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, 25*time.Second)
+	}
+}
+
+func TestNewVulnersClient_IdleTimeoutJitter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{
+			IdleConnections:          10,
+			IdleConnTimeout:          config.Duration(90 * time.Second),
+			IdleTimeoutJitterPercent: 10,
+		},
+	}
+
+	for range 20 {
+		c := NewVulnersClient(cfg, logger, nil)
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("Transport = %T, want *http.Transport", c.httpClient.Transport)
+		}
+		if d := transport.IdleConnTimeout; d < 81*time.Second || d > 99*time.Second {
+			t.Fatalf("IdleConnTimeout = %v, want within 90s ±10%%", d)
+		}
+	}
+}
+
+func TestVulnersClient_DoStream_TimeoutAndCancelMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := metrics.New()
+	c := NewVulnersClient(cfg, logger, m)
+
+	// Deadline exceeded: genuinely slow upstream.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.DoStream(ctx, http.MethodGet, srv.URL+"/slow", http.Header{}, nil); err == nil {
+		t.Fatal("DoStream() expected timeout error, got nil")
+	}
+
+	// Canceled with a deadline cause: the service's time-to-first-byte bound.
+	ctx2, cancel2 := context.WithCancelCause(context.Background())
+	time.AfterFunc(20*time.Millisecond, func() { cancel2(context.DeadlineExceeded) })
+	if _, err := c.DoStream(ctx2, http.MethodGet, srv.URL+"/slow", http.Header{}, nil); err == nil {
+		t.Fatal("DoStream() expected timeout error, got nil")
+	}
+
+	// Plain cancellation: client disconnected.
+	ctx3, cancel3 := context.WithCancel(context.Background())
+	cancel3()
+	if _, err := c.DoStream(ctx3, http.MethodGet, srv.URL+"/slow", http.Header{}, nil); err == nil {
+		t.Fatal("DoStream() expected cancellation error, got nil")
+	}
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	counts := make(map[string]float64)
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			if metric.GetCounter() != nil {
+				counts[f.GetName()] += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	if v := counts["vulners_proxy_upstream_timeouts_total"]; v != 2 {
+		t.Errorf("upstream_timeouts_total = %v, want 2", v)
+	}
+	if v := counts["vulners_proxy_upstream_client_canceled_total"]; v != 1 {
+		t.Errorf("upstream_client_canceled_total = %v, want 1", v)
+	}
+}
+
+func TestVulnersClient_DoStream_AuthFailureMetric(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{IdleConnections: 10},
+	}
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	m := metrics.New()
+	c := NewVulnersClient(cfg, logger, m)
+
+	for _, path := range []string{"/unauthorized", "/unauthorized", "/forbidden", "/missing"} {
+		header := http.Header{"X-Api-Key": {"secret-key"}}
+		resp, err := c.DoStream(context.Background(), http.MethodGet, srv.URL+path, header, nil)
+		if err != nil {
+			t.Fatalf("DoStream(%s) error = %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "vulners_proxy_upstream_auth_failures_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			for _, lp := range metric.GetLabel() {
+				got[lp.GetName()+"="+lp.GetValue()] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+	want := map[string]float64{"status=401": 2, "status=403": 1}
+	if len(got) != len(want) || got["status=401"] != 2 || got["status=403"] != 1 {
+		t.Errorf("upstream_auth_failures_total = %v, want %v", got, want)
+	}
+	if !strings.Contains(logs.String(), "upstream rejected API key") {
+		t.Error("expected a warning for the rejected key")
+	}
+	if strings.Contains(logs.String(), "secret-key") {
+		t.Error("log output must not contain the API key")
+	}
+}
+
+func TestVulnersClient_DoStream_SlowWarning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var logs strings.Builder
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{
+			IdleConnections: 10,
+			SlowThreshold:   config.Duration(10 * time.Millisecond),
+		},
+	}
+	c := NewVulnersClient(cfg, slog.New(slog.NewTextHandler(&logs, nil)), nil)
+
+	for _, path := range []string{"/fast?apiKey=secret", "/slow?apiKey=secret"} {
+		resp, err := c.DoStream(context.Background(), http.MethodGet, srv.URL+path, http.Header{}, nil)
+		if err != nil {
+			t.Fatalf("DoStream(%s) error = %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	out := logs.String()
+	if n := strings.Count(out, "upstream slow"); n != 1 {
+		t.Fatalf("slow warnings = %d, want 1; logs:\n%s", n, out)
+	}
+	if !strings.Contains(out, "path=/slow") || !strings.Contains(out, "method=GET") {
+		t.Errorf("slow warning missing method or path; logs:\n%s", out)
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("slow warning leaked the query string; logs:\n%s", out)
+	}
+}
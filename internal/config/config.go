@@ -2,13 +2,19 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	toml "github.com/pelletier/go-toml/v2"
+
+	"vulners-proxy-go/internal/routes"
 )
 
 // configSearchPaths lists paths checked in order when no explicit config is given.
@@ -19,11 +25,12 @@ var configSearchPaths = []string{
 
 // CLI holds command-line arguments parsed by Kong.
 type CLI struct {
-	Config   string `kong:"short='c',help='Path to TOML config file.',env='CONFIG_PATH'"`
-	Host     string `kong:"help='Listen host (overrides config).',env='HOST'"`
-	Port     int    `kong:"short='p',help='Listen port (overrides config).',env='PORT'"`
-	APIKey   string `kong:"help='Vulners API key (overrides config).',env='VULNERS_API_KEY'"`
-	LogLevel string `kong:"help='Log level: debug|info|warn|error (overrides config).',env='LOG_LEVEL'"`
+	Config      string `kong:"short='c',help='Path or https:// URL of the TOML config.',env='CONFIG_PATH'"`
+	ConfigToken string `kong:"help='Bearer token for fetching an https:// config.',env='CONFIG_TOKEN'"`
+	Host        string `kong:"help='Listen host (overrides config).',env='HOST'"`
+	Port        int    `kong:"short='p',help='Listen port (overrides config).',env='PORT'"`
+	APIKey      string `kong:"help='Vulners API key (overrides config).',env='VULNERS_API_KEY'"`
+	LogLevel    string `kong:"help='Log level: debug|info|warn|error (overrides config).',env='LOG_LEVEL'"`
 }
 
 // Config is the top-level application configuration.
@@ -31,8 +38,18 @@ type Config struct {
 	Server   ServerConfig   `toml:"server"`
 	Vulners  VulnersConfig  `toml:"vulners"`
 	Upstream UpstreamConfig `toml:"upstream"`
+	Shadow   ShadowConfig   `toml:"shadow"`
 	Log      LogConfig      `toml:"log"`
 	Metrics  MetricsConfig  `toml:"metrics"`
+	Startup  StartupConfig  `toml:"startup"`
+	Admin    AdminConfig    `toml:"admin"`
+
+	Maintenance MaintenanceConfig `toml:"maintenance"`
+	Liveness    LivenessConfig    `toml:"liveness"`
+	StatusCheck StatusCheckConfig `toml:"status_check"`
+	Debug       DebugConfig       `toml:"debug"`
+
+	ResponseTransform ResponseTransformConfig `toml:"response_transform"`
 
 	filePath string // resolved config file path (unexported)
 }
@@ -43,30 +60,449 @@ type ServerConfig struct {
 	Port         int             `toml:"port"` // 0 means "use default" (8000); TOML cannot distinguish 0 from unset
 	BodyMaxBytes int64           `toml:"body_max_bytes"`
 	RateLimit    RateLimitConfig `toml:"rate_limit"`
+
+	// BodyLimits override BodyMaxBytes for matching requests. When several
+	// match, the one with the longest path prefix wins.
+	BodyLimits []BodyLimitConfig `toml:"body_limits"`
+
+	// Inbound connection timeouts. Defaults: 30s, 10s, and 120s. There is
+	// deliberately no write timeout, so long streamed responses are not cut.
+	ReadTimeout       Duration `toml:"read_timeout"`
+	ReadHeaderTimeout Duration `toml:"read_header_timeout"`
+	IdleTimeout       Duration `toml:"idle_timeout"`
+
+	// TCPKeepAlive is the keep-alive probe period set on accepted client
+	// connections, so dead peers behind NAT are detected. Default 30s;
+	// TCPKeepAliveSeconds is its integer form. DisableTCPKeepAlive turns
+	// probes off.
+	TCPKeepAlive        Duration `toml:"tcp_keepalive"`
+	TCPKeepAliveSeconds int      `toml:"tcp_keepalive_seconds"`
+	DisableTCPKeepAlive bool     `toml:"disable_tcp_keepalive"`
+
+	// CorrelationIDHeader names the request header a client may use to
+	// supply its own request ID; a well-formed value replaces the generated
+	// one in logs and responses and is forwarded upstream. Defaults to
+	// "X-Correlation-Id".
+	CorrelationIDHeader string `toml:"correlation_id_header"`
+
+	// MaxInFlight sheds load: while more requests than this are being
+	// processed, new /api/* requests get 503 with a Retry-After of
+	// ShedRetryAfter (default 1s). 0 disables shedding.
+	MaxInFlight    int      `toml:"max_in_flight"`
+	ShedRetryAfter Duration `toml:"shed_retry_after"`
+
+	// MaxPathLength rejects a request with 414 when its escaped URL path is
+	// longer than this many bytes, before routing or any upstream work.
+	// Defaults to 8192.
+	MaxPathLength int `toml:"max_path_length"`
+	// MaxQueryParams rejects a request with 400 when its query string has
+	// more parameters than this, counting repeated names each time. The
+	// check sees only what the client sent, before any API key or default
+	// parameters are added. Defaults to 100.
+	MaxQueryParams int `toml:"max_query_params"`
+
+	// ExposeVersion sets X-Proxy-Version to the build version on every
+	// response. Unset means true; read it with VersionExposed.
+	ExposeVersion *bool `toml:"expose_version"`
+
+	// Environment names the deployment (e.g. "prod" or "staging"). It is
+	// reported by GET /proxy/status and, with ExposeEnvironment, sent as
+	// X-Environment on every response.
+	Environment       string `toml:"environment"`
+	ExposeEnvironment bool   `toml:"expose_environment"`
+
+	// ProxyMode is "stream" (default; upstream bodies are copied to the
+	// client as they arrive) or "buffer" (the whole body is read before the
+	// status is sent, so an upstream failure mid-body becomes a clean 502).
+	// Buffered bodies larger than BufferMaxBytes fall back to streaming.
+	ProxyMode      string `toml:"proxy_mode"`
+	BufferMaxBytes int64  `toml:"buffer_max_bytes"`
+	// ValidateJSONResponses answers 502 instead of relaying a buffered
+	// application/json body that is not valid JSON. It has no effect in
+	// stream mode or on bodies larger than BufferMaxBytes, which are
+	// streamed.
+	ValidateJSONResponses bool `toml:"validate_json_responses"`
+
+	// EnableH2C accepts cleartext HTTP/2 with prior knowledge
+	// alongside HTTP/1.1 on the plain listener.
+	EnableH2C bool `toml:"enable_h2c"`
+
+	// StripResponseHeaders are removed from proxied responses even if the
+	// upstream response header allowlist would otherwise forward them.
+	StripResponseHeaders []string `toml:"strip_response_headers"`
+	// SetResponseHeaders are set on proxied responses, replacing any value
+	// forwarded from upstream. Applied after StripResponseHeaders.
+	SetResponseHeaders map[string]string `toml:"set_response_headers"`
+
+	// SecurityHeaders overrides the X-Frame-Options and
+	// X-Content-Type-Options headers added to every response.
+	SecurityHeaders SecurityHeadersConfig `toml:"security_headers"`
+
+	// RequiredParams maps a request path to query parameters that must be
+	// present; requests missing any of them are rejected with 400 before
+	// reaching upstream. Paths match exactly, ignoring a trailing slash.
+	RequiredParams map[string][]string `toml:"required_params"`
+
+	// RequireContentTypeOnPost rejects POST, PUT and PATCH requests without
+	// a Content-Type header with 400 before reaching upstream.
+	RequireContentTypeOnPost bool `toml:"require_content_type_on_post"`
+
+	// RejectGetBody rejects GET, HEAD and DELETE requests that carry a body
+	// (a non-zero Content-Length or a chunked body) with 400 before
+	// reaching upstream.
+	RejectGetBody bool `toml:"reject_get_body"`
+
+	// ExposeUpstreamTiming sets X-Upstream-Duration-Ms on proxied responses
+	// to the time the upstream took to return response headers. Off by
+	// default so backend timing is not disclosed.
+	ExposeUpstreamTiming bool `toml:"expose_upstream_timing"`
+
+	// UnavailableResponse replaces the error sent when the upstream cannot
+	// be reached. Unset fields keep the default behavior.
+	UnavailableResponse UnavailableResponseConfig `toml:"unavailable_response"`
+}
+
+// UnavailableResponseConfig customizes the response for upstream connectivity
+// failures: DNS errors, refused or dropped connections, and timeouts.
+type UnavailableResponseConfig struct {
+	// StatusCode replaces the default 502 or 504; 0 keeps it.
+	StatusCode int `toml:"status_code"`
+	// Body is a JSON document sent instead of the default error body.
+	// "{request_id}" is replaced with the request ID, JSON-escaped.
+	Body string `toml:"body"`
+}
+
+// BodyLimitConfig sets the request body limit for one method and path prefix
+// combination.
+type BodyLimitConfig struct {
+	// Methods the override applies to; empty means all methods.
+	Methods    []string `toml:"methods"`
+	PathPrefix string   `toml:"path_prefix"`
+	// MaxBytes is the limit for matching requests; 0 removes the limit.
+	MaxBytes int64 `toml:"max_bytes"`
+}
+
+// SecurityHeadersConfig overrides the security headers added to every
+// response. An absent key keeps the default value; an empty string omits the
+// header. Read them with Headers.
+type SecurityHeadersConfig struct {
+	FrameOptions       *string `toml:"frame_options"`        // X-Frame-Options; default "DENY"
+	ContentTypeOptions *string `toml:"content_type_options"` // X-Content-Type-Options; default "nosniff"
 }
 
 // RateLimitConfig controls per-IP request rate limiting.
 type RateLimitConfig struct {
 	Enabled           bool    `toml:"enabled"`
 	RequestsPerSecond float64 `toml:"requests_per_second"`
+	// Algorithm is "token_bucket" (default; allows bursts up to the
+	// per-second rate) or "sliding_window" (no bursts across windows).
+	Algorithm string `toml:"algorithm"`
+	// RequestsPerMinute additionally caps each IP over a sliding one-minute
+	// window, so short bursts at the per-second rate are allowed but not
+	// sustained. A request must pass both limits. 0 disables the cap.
+	RequestsPerMinute float64 `toml:"requests_per_minute"`
+	// ExemptPaths lists path prefixes that bypass rate limiting. Defaults to
+	// the health endpoints so monitoring does not use up the budget.
+	ExemptPaths []string `toml:"exempt_paths"`
 }
 
 // VulnersConfig holds Vulners API credentials.
 type VulnersConfig struct {
 	APIKey string `toml:"api_key"`
+	// SecondaryAPIKey is tried once when upstream rejects APIKey with 401,
+	// allowing zero-downtime key rotation.
+	SecondaryAPIKey string `toml:"secondary_api_key"`
+	// APIKeyHeader is the request header clients send their key in when
+	// APIKey is empty. It is never forwarded upstream; the key always goes
+	// upstream in UpstreamAPIKeyHeader, which is also the default here.
+	APIKeyHeader string `toml:"api_key_header"`
+	// MinKeyLength rejects a configured APIKey or SecondaryAPIKey shorter
+	// than this, to catch a truncated paste. 0 means the default of 8.
+	MinKeyLength int `toml:"min_key_length"`
+	// KeyPrecedence decides which key wins when both APIKey and a client
+	// key in APIKeyHeader are present: "config" (default) or "header".
+	KeyPrecedence string `toml:"key_precedence"`
+	// TenantKeys maps a tenant ID, sent in TenantHeader, to the API key used
+	// for that tenant's requests. Requests without the header, or with an
+	// unknown tenant ID, use the default key resolution.
+	TenantKeys map[string]string `toml:"tenant_keys"`
+	// TenantHeader is the request header selecting a TenantKeys entry. It
+	// is never forwarded upstream. Defaults to "X-Tenant-Id".
+	TenantHeader string `toml:"tenant_header"`
 }
 
+// UpstreamAPIKeyHeader is the header the API key is sent upstream in, and
+// the default for vulners.api_key_header.
+const UpstreamAPIKeyHeader = "X-Api-Key"
+
+// MinUpstreamTimeout is the smallest upstream.timeout considered sane; real
+// Vulners searches regularly take longer, so a lower value is almost always
+// a unit mistake.
+const MinUpstreamTimeout = 5 * time.Second
+
+// defaultMinKeyLength is the default for vulners.min_key_length.
+const defaultMinKeyLength = 8
+
 // UpstreamConfig holds upstream connection settings.
 type UpstreamConfig struct {
+	// BaseURL is the upstream origin. A path in it is prepended to every
+	// request path; query strings and fragments are rejected.
 	BaseURL         string `toml:"base_url"`
-	TimeoutSeconds  int    `toml:"timeout_seconds"`
 	IdleConnections int    `toml:"idle_connections"`
+	// CanaryBaseURL receives requests carrying "X-Canary: true" instead of
+	// BaseURL, for trying a new mirror with controlled traffic. The same
+	// rules as BaseURL apply, including the host allowlist. Empty disables.
+	CanaryBaseURL string `toml:"canary_base_url"`
+
+	// Timeout bounds the time until the upstream starts responding.
+	// Streaming the response body afterwards is not limited by it.
+	Timeout Duration `toml:"timeout"`
+	// ResponseHeaderTimeout bounds the wait for upstream response headers
+	// once the request has been written. Defaults to Timeout.
+	ResponseHeaderTimeout Duration `toml:"response_header_timeout"`
+	// IdleConnTimeout closes pooled upstream connections left idle longer
+	// than this. Keep it below the upstream's own idle timeout, or reused
+	// connections may be reset. Defaults to 90s.
+	IdleConnTimeout Duration `toml:"idle_conn_timeout"`
+	// SuccessWindow is the sliding window over which the upstream success
+	// ratio (2xx and 3xx answers among all calls) is computed for the
+	// vulners_proxy_upstream_success_ratio gauge and GET /proxy/status.
+	// Defaults to 1m.
+	SuccessWindow Duration `toml:"success_window"`
+
+	// Integer forms of the timeouts above, kept so existing config files
+	// keep working. Each is folded into its Duration field on load; setting
+	// both forms of the same timeout is an error.
+	TimeoutSeconds               int `toml:"timeout_seconds"`
+	ResponseHeaderTimeoutSeconds int `toml:"response_header_timeout_seconds"`
+	IdleConnTimeoutSeconds       int `toml:"idle_conn_timeout_seconds"`
+	QueueTimeoutMs               int `toml:"queue_timeout_ms"`
+	SlowThresholdMs              int `toml:"slow_threshold_ms"`
+
+	// PathTimeouts overrides Timeout for requests whose path equals a given
+	// prefix or is nested under it; the longest matching prefix wins. Values
+	// are duration strings with a unit, such as "5s". Values above
+	// ResponseHeaderTimeout are capped by it unless it is left at its
+	// default, which then grows to the largest value here.
+	PathTimeouts map[string]Duration `toml:"path_timeouts"`
+
+	// ForwardHeaderPrefixes lists case-insensitive request header prefixes
+	// that are forwarded upstream in addition to the fixed allowlist.
+	// Defaults to ["x-vulners-"].
+	ForwardHeaderPrefixes []string `toml:"forward_header_prefixes"`
+	// PreserveHost sends the client's Host header upstream instead of the
+	// base_url host, for mirrors that route on Host. The connection and TLS
+	// verification still target base_url.
+	PreserveHost bool `toml:"preserve_host"`
+
+	// OnRedirect decides what happens to an upstream 3xx redirect, which is
+	// never followed: "error" (default) answers 502, "relay" passes it to
+	// the client with Location kept only if it targets an allowed host.
+	OnRedirect string `toml:"on_redirect"`
+
+	// StatusRemap replaces upstream status codes before they reach the
+	// client, keyed by the upstream code as a string (e.g. "501" = 404), to
+	// smooth over differences between mirror versions. Empty by default.
+	StatusRemap map[string]int `toml:"status_remap"`
+
+	// DefaultAccept is sent upstream as the Accept header when the client
+	// omits it. Defaults to "application/json".
+	DefaultAccept string `toml:"default_accept"`
+
+	// ForceAcceptEncoding, when set, replaces the client's Accept-Encoding
+	// on upstream requests, e.g. "identity" to always get uncompressed
+	// bodies. The response is relayed as upstream encoded it. Empty keeps
+	// the client's value.
+	ForceAcceptEncoding string `toml:"force_accept_encoding"`
+
+	// DefaultQueryParams are added to every upstream request URL unless the
+	// client already supplied a value for the same parameter.
+	DefaultQueryParams map[string]string `toml:"default_query_params"`
+
+	// MaxConcurrentRequests caps in-flight upstream requests; 0 means unlimited.
+	MaxConcurrentRequests int `toml:"max_concurrent_requests"`
+	// MaxQueuedRequests bounds how many requests may wait for a free slot;
+	// 0 means requests are rejected immediately when all slots are busy.
+	MaxQueuedRequests int      `toml:"max_queued_requests"`
+	QueueTimeout      Duration `toml:"queue_timeout"`
+
+	// IdleTimeoutJitterPercent randomizes the idle connection timeout and TCP
+	// keep-alive interval by up to ±N% per process, so a fleet of proxies
+	// does not reconnect to upstream all at once. 0 disables jitter.
+	IdleTimeoutJitterPercent int `toml:"idle_timeout_jitter_percent"`
+
+	// MaxHeaderBytes rejects a request with 431 when the headers that would
+	// be sent upstream, after filtering and key injection, exceed this many
+	// bytes. 0 disables the check.
+	MaxHeaderBytes int `toml:"max_header_bytes"`
+
+	// SlowThreshold logs a warning for each upstream call that takes longer
+	// than this to return response headers; 0 disables the warning.
+	SlowThreshold Duration `toml:"slow_threshold"`
+
+	// StrictTimeouts rejects a non-zero Timeout below MinUpstreamTimeout at
+	// load time instead of only warning at startup.
+	StrictTimeouts bool `toml:"strict_timeouts"`
+
+	// DisableKeepAlive forces a fresh upstream connection per request.
+	// Debug only: it adds a TCP and TLS handshake to every request.
+	DisableKeepAlive bool `toml:"disable_keepalive"`
 }
 
 // LogConfig holds logging settings.
 type LogConfig struct {
 	Level  string `toml:"level"`
 	Format string `toml:"format"`
+
+	// Output is "stdout" (default), "stderr", or "file". With "file" the log
+	// is written to FilePath and rotated once it reaches MaxSizeMB; at most
+	// MaxBackups rotated files no older than MaxAgeDays are kept (0 keeps
+	// all of them).
+	Output     string `toml:"output"`
+	FilePath   string `toml:"file_path"`
+	MaxSizeMB  int    `toml:"max_size_mb"`
+	MaxBackups int    `toml:"max_backups"`
+	MaxAgeDays int    `toml:"max_age_days"`
+
+	// AuditEnabled turns on a separate stream of security-relevant events
+	// (auth failures, rate-limit rejections). AuditOutput is "stdout",
+	// "stderr", or a file path opened in append mode.
+	AuditEnabled bool   `toml:"audit_enabled"`
+	AuditOutput  string `toml:"audit_output"`
+
+	// WarnQueryAPIKey logs a warning when a client passes an API key as a
+	// query parameter (apiKey, api_key, ...) instead of the X-Api-Key
+	// header. The parameter is stripped either way.
+	WarnQueryAPIKey bool `toml:"warn_query_api_key"`
+
+	// RedactQueryParams names query parameters whose values are replaced
+	// with [REDACTED] wherever an upstream URL is logged. API keys are
+	// always redacted.
+	RedactQueryParams []string `toml:"redact_query_params"`
+
+	// UseRouteTemplate logs the matched route (e.g. "/api/v3/*") as the
+	// request path instead of the raw URL path, which is then logged as
+	// raw_path at debug level only.
+	UseRouteTemplate bool `toml:"use_route_template"`
+
+	// StreamProgressInterval logs, at debug level, the bytes copied and time
+	// elapsed for a streamed response body every interval once it has been
+	// streaming that long, so a hung long transfer is visible before it
+	// ends. 0 disables it.
+	StreamProgressInterval Duration `toml:"stream_progress_interval"`
+
+	// SampleRate is the share (0.0–1.0) of successful requests whose access
+	// log line is written at info level; the rest are logged at debug.
+	// Errors and requests slower than upstream.slow_threshold are always
+	// logged at info. Unset means 1.0; read it with RequestSampleRate.
+	SampleRate *float64 `toml:"sample_rate"`
+}
+
+// RequestSampleRate returns log.sample_rate, or 1 when it is not set.
+func (c *LogConfig) RequestSampleRate() float64 {
+	return valueOr(c.SampleRate, 1)
+}
+
+// ShadowConfig mirrors proxied GET requests to a second upstream to compare
+// it with the primary. Shadow responses are discarded; the client is always
+// answered from the primary.
+type ShadowConfig struct {
+	Enabled bool   `toml:"enabled"`
+	BaseURL string `toml:"base_url"`
+	// Timeout bounds each shadow request, including reading its body.
+	// Default 30s.
+	Timeout Duration `toml:"timeout"`
+	// MaxInFlight caps concurrent shadow requests; requests arriving while
+	// it is reached are not mirrored. Default 100.
+	MaxInFlight int `toml:"max_in_flight"`
+}
+
+// StartupConfig controls checks that run once before the server accepts
+// traffic.
+type StartupConfig struct {
+	// SelfTest sends one request to SelfTestPath upstream at startup to
+	// verify DNS, TLS, and the configured API key.
+	SelfTest        bool     `toml:"self_test"`
+	SelfTestPath    string   `toml:"self_test_path"`
+	SelfTestTimeout Duration `toml:"self_test_timeout"`
+	// SelfTestTimeoutSeconds is the legacy integer form of SelfTestTimeout.
+	SelfTestTimeoutSeconds int `toml:"self_test_timeout_seconds"`
+	// FailOnSelfTest aborts startup when the self-test fails; otherwise the
+	// proxy logs an error and starts anyway.
+	FailOnSelfTest bool `toml:"fail_on_self_test"`
+}
+
+// AdminConfig holds the credentials for the /proxy/* admin endpoints.
+type AdminConfig struct {
+	// Token is sent as a bearer token to the admin endpoints; those that
+	// need it are not registered when it is empty. Read it through
+	// Config.AdminToken, which falls back to maintenance.admin_token.
+	Token string `toml:"token"`
+}
+
+// MaintenanceConfig controls maintenance mode, in which /api/* requests are
+// answered with 503 instead of being forwarded.
+type MaintenanceConfig struct {
+	// Enabled is the initial state; it is re-read on SIGHUP and can be
+	// flipped at runtime through POST /proxy/maintenance.
+	Enabled    bool     `toml:"enabled"`
+	Message    string   `toml:"message"`
+	RetryAfter Duration `toml:"retry_after"`
+	// AdminToken is the legacy location of admin.token, from when it only
+	// authorized POST /proxy/maintenance.
+	AdminToken string `toml:"admin_token"`
+}
+
+// Liveness defaults.
+const (
+	defaultHeartbeatInterval = time.Second
+	defaultStallThreshold    = 10 * time.Second
+)
+
+// LivenessConfig makes /healthz detect a wedged process. A background
+// goroutine ticks every HeartbeatInterval; when it has not ticked within
+// StallThreshold, /healthz answers 503 so the orchestrator restarts the
+// proxy.
+type LivenessConfig struct {
+	Enabled           bool     `toml:"enabled"`
+	HeartbeatInterval Duration `toml:"heartbeat_interval"`
+	StallThreshold    Duration `toml:"stall_threshold"`
+	// Integer second forms of the durations above.
+	HeartbeatIntervalSeconds int `toml:"heartbeat_interval_seconds"`
+	StallThresholdSeconds    int `toml:"stall_threshold_seconds"`
+}
+
+// StatusCheckConfig controls the live upstream check behind
+// GET /proxy/status?check=true. The check sends the same request as the
+// startup self-test (startup.self_test_path). At most one check runs per
+// MinInterval; requests in between are answered with the previous result.
+type StatusCheckConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Timeout bounds a single check. Default 5s.
+	Timeout Duration `toml:"timeout"`
+	// MinInterval is the minimum time between two checks. Default 10s.
+	MinInterval Duration `toml:"min_interval"`
+}
+
+// DebugConfig holds test-only features for exercising client timeout and
+// retry handling. Nothing here takes effect unless Enabled is set, and
+// active features are listed in /proxy/status.
+type DebugConfig struct {
+	Enabled bool `toml:"enabled"`
+	// InjectLatency delays every proxied request by a fixed amount before it
+	// is forwarded upstream.
+	InjectLatency Duration `toml:"inject_latency"`
+	// InjectLatencyMs is the integer millisecond form of InjectLatency.
+	InjectLatencyMs int `toml:"inject_latency_ms"`
+}
+
+// LatencyInjection returns the artificial delay to add before forwarding,
+// or 0 when debug features are off.
+func (d *DebugConfig) LatencyInjection() time.Duration {
+	if !d.Enabled {
+		return 0
+	}
+	return d.InjectLatency.Std()
 }
 
 // MetricsConfig holds Prometheus metrics settings.
@@ -75,9 +511,18 @@ type MetricsConfig struct {
 	Path    string `toml:"path"`
 }
 
+// ResponseTransformConfig controls optional trimming of JSON responses.
+type ResponseTransformConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Paths        []string `toml:"paths"`          // path prefixes the transform applies to
+	StripFields  []string `toml:"strip_fields"`   // top-level JSON fields removed from responses
+	MaxBodyBytes int64    `toml:"max_body_bytes"` // larger responses are streamed untransformed
+}
+
 // Load reads the TOML config file and applies CLI overrides.
 // When no explicit path is given (via --config or CONFIG_PATH), it searches
-// /etc/vulners-proxy/config.toml then configs/config.toml.
+// /etc/vulners-proxy/config.toml then configs/config.toml. An https:// URL
+// is fetched instead of read from disk; other URL schemes are rejected.
 func Load(cli *CLI) (*Config, error) {
 	path := cli.Config
 	if path == "" {
@@ -87,17 +532,22 @@ func Load(cli *CLI) (*Config, error) {
 		return nil, fmt.Errorf("config: no config file found (searched %v)", configSearchPaths)
 	}
 
-	data, err := os.ReadFile(path)
+	data, err := readConfig(path, cli.ConfigToken)
 	if err != nil {
-		return nil, fmt.Errorf("config: read %s: %w", path, err)
+		return nil, err
 	}
 
 	var cfg Config
 	if err := toml.Unmarshal(data, &cfg); err != nil {
+		if isRemote(path) {
+			return nil, fmt.Errorf("config: parse remote config: %w", err)
+		}
 		return nil, fmt.Errorf("config: parse %s: %w", path, err)
 	}
 
-	cfg.filePath = path
+	if !isRemote(path) {
+		cfg.filePath = path
+	}
 	cfg.applyCLI(cli)
 
 	if err := cfg.validate(); err != nil {
@@ -105,6 +555,9 @@ func Load(cli *CLI) (*Config, error) {
 	}
 
 	cfg.setDefaults()
+	if err := cfg.validateResolved(); err != nil {
+		return nil, fmt.Errorf("config: validate: %w", err)
+	}
 	return &cfg, nil
 }
 
@@ -129,34 +582,329 @@ func (c *Config) validate() error {
 		return fmt.Errorf("vulners.api_key contains placeholder value; set a real key or leave empty for per-request X-Api-Key mode")
 	}
 
+	if c.Vulners.SecondaryAPIKey != "" {
+		if c.Vulners.SecondaryAPIKey == "YOUR_API_KEY_HERE" {
+			return fmt.Errorf("vulners.secondary_api_key contains placeholder value")
+		}
+		if c.Vulners.APIKey == "" {
+			return fmt.Errorf("vulners.secondary_api_key requires vulners.api_key to be set")
+		}
+		if c.Vulners.SecondaryAPIKey == c.Vulners.APIKey {
+			return fmt.Errorf("vulners.secondary_api_key must differ from vulners.api_key")
+		}
+	}
+
+	if c.Vulners.MinKeyLength < 0 {
+		return fmt.Errorf("vulners.min_key_length must be non-negative; got %d", c.Vulners.MinKeyLength)
+	}
+	minLen := c.Vulners.MinKeyLength
+	if minLen == 0 {
+		minLen = defaultMinKeyLength
+	}
+	if k := c.Vulners.APIKey; k != "" && len(k) < minLen {
+		return fmt.Errorf("vulners.api_key is %d characters, shorter than vulners.min_key_length (%d); check for a truncated key", len(k), minLen)
+	}
+	if k := c.Vulners.SecondaryAPIKey; k != "" && len(k) < minLen {
+		return fmt.Errorf("vulners.secondary_api_key is %d characters, shorter than vulners.min_key_length (%d); check for a truncated key", len(k), minLen)
+	}
+
+	if strings.ContainsAny(c.Vulners.APIKeyHeader, " \t\r\n:") {
+		return fmt.Errorf("vulners.api_key_header is not a valid header name: %q", c.Vulners.APIKeyHeader)
+	}
+
+	switch c.Vulners.KeyPrecedence {
+	case "config", "header", "":
+		// valid
+	default:
+		return fmt.Errorf("vulners.key_precedence must be one of: config, header; got %q", c.Vulners.KeyPrecedence)
+	}
+
+	for tenant, k := range c.Vulners.TenantKeys {
+		if tenant == "" {
+			return fmt.Errorf("vulners.tenant_keys must not contain an empty tenant ID")
+		}
+		if k == "YOUR_API_KEY_HERE" {
+			return fmt.Errorf("vulners.tenant_keys[%q] contains placeholder value", tenant)
+		}
+		if len(k) < minLen {
+			return fmt.Errorf("vulners.tenant_keys[%q] is %d characters, shorter than vulners.min_key_length (%d); check for a truncated key", tenant, len(k), minLen)
+		}
+	}
+	if strings.ContainsAny(c.Vulners.TenantHeader, " \t\r\n:") {
+		return fmt.Errorf("vulners.tenant_header is not a valid header name: %q", c.Vulners.TenantHeader)
+	}
+	keyHeader := c.Vulners.APIKeyHeader
+	if keyHeader == "" {
+		keyHeader = UpstreamAPIKeyHeader
+	}
+	if strings.EqualFold(c.Vulners.TenantHeader, keyHeader) {
+		return fmt.Errorf("vulners.tenant_header must differ from vulners.api_key_header; got %q", c.Vulners.TenantHeader)
+	}
+
 	// Upstream URL: required and must be HTTPS.
 	if c.Upstream.BaseURL == "" {
 		return fmt.Errorf("upstream.base_url is required")
 	}
-	u, err := url.Parse(c.Upstream.BaseURL)
-	if err != nil {
-		return fmt.Errorf("upstream.base_url is not a valid URL: %w", err)
+	if err := checkBaseURL("upstream.base_url", c.Upstream.BaseURL); err != nil {
+		return err
 	}
-	if u.Scheme != "https" {
-		return fmt.Errorf("upstream.base_url must use HTTPS; got %q", c.Upstream.BaseURL)
+	if c.Upstream.CanaryBaseURL != "" {
+		if err := checkBaseURL("upstream.canary_base_url", c.Upstream.CanaryBaseURL); err != nil {
+			return err
+		}
+	}
+
+	if c.Shadow.Enabled {
+		if c.Shadow.BaseURL == "" {
+			return fmt.Errorf("shadow.base_url is required when shadow.enabled is set")
+		}
+		if err := checkBaseURL("shadow.base_url", c.Shadow.BaseURL); err != nil {
+			return err
+		}
+	}
+	if c.Shadow.Timeout < 0 {
+		return fmt.Errorf("shadow.timeout must be non-negative; got %s", c.Shadow.Timeout.Std())
+	}
+	if c.Shadow.MaxInFlight < 0 {
+		return fmt.Errorf("shadow.max_in_flight must be non-negative; got %d", c.Shadow.MaxInFlight)
 	}
 
 	// Numeric bounds.
 	if c.Server.Port < 0 || c.Server.Port > 65535 {
 		return fmt.Errorf("server.port must be 0–65535; got %d", c.Server.Port)
 	}
+	if err := checkDuration("server.tcp_keepalive", c.Server.TCPKeepAlive,
+		"server.tcp_keepalive_seconds", c.Server.TCPKeepAliveSeconds); err != nil {
+		return err
+	}
 	if c.Server.BodyMaxBytes < 0 {
 		return fmt.Errorf("server.body_max_bytes must be non-negative; got %d", c.Server.BodyMaxBytes)
 	}
-	if c.Upstream.TimeoutSeconds < 0 {
-		return fmt.Errorf("upstream.timeout_seconds must be non-negative; got %d", c.Upstream.TimeoutSeconds)
+	if strings.ContainsAny(c.Server.CorrelationIDHeader, " \t\r\n:") {
+		return fmt.Errorf("server.correlation_id_header is not a valid header name: %q", c.Server.CorrelationIDHeader)
+	}
+	if c.Server.MaxInFlight < 0 {
+		return fmt.Errorf("server.max_in_flight must be non-negative; got %d", c.Server.MaxInFlight)
+	}
+	if c.Server.ShedRetryAfter < 0 {
+		return fmt.Errorf("server.shed_retry_after must be non-negative; got %s", c.Server.ShedRetryAfter.Std())
+	}
+	if strings.ContainsFunc(c.Server.Environment, unicode.IsControl) {
+		return fmt.Errorf("server.environment must not contain control characters; got %q", c.Server.Environment)
+	}
+	if c.Server.ExposeEnvironment && c.Server.Environment == "" {
+		return fmt.Errorf("server.expose_environment requires server.environment")
+	}
+	if c.Server.MaxPathLength < 0 {
+		return fmt.Errorf("server.max_path_length must be non-negative; got %d", c.Server.MaxPathLength)
+	}
+	if c.Server.MaxQueryParams < 0 {
+		return fmt.Errorf("server.max_query_params must be non-negative; got %d", c.Server.MaxQueryParams)
+	}
+	for _, bl := range c.Server.BodyLimits {
+		if !strings.HasPrefix(bl.PathPrefix, "/") {
+			return fmt.Errorf("server.body_limits path_prefix must start with '/'; got %q", bl.PathPrefix)
+		}
+		if bl.MaxBytes < 0 {
+			return fmt.Errorf("server.body_limits max_bytes must be non-negative; got %d", bl.MaxBytes)
+		}
+		for _, m := range bl.Methods {
+			if strings.TrimSpace(m) == "" {
+				return fmt.Errorf("server.body_limits methods must not contain empty method names")
+			}
+		}
+	}
+	switch c.Upstream.OnRedirect {
+	case "error", "relay", "":
+		// valid
+	default:
+		return fmt.Errorf("upstream.on_redirect must be one of: error, relay; got %q", c.Upstream.OnRedirect)
+	}
+	for from, to := range c.Upstream.StatusRemap {
+		if code, err := strconv.Atoi(from); err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("upstream.status_remap keys must be HTTP status codes (100-599); got %q", from)
+		}
+		// Redirects would lack a Location, and 204 forbids the relayed body.
+		if to < 200 || to > 599 || (to >= 300 && to < 400) || to == 204 {
+			return fmt.Errorf("upstream.status_remap[%q] must be a 2xx, 4xx or 5xx status other than 204; got %d", from, to)
+		}
+	}
+	switch c.Server.ProxyMode {
+	case "stream", "buffer", "":
+		// valid
+	default:
+		return fmt.Errorf("server.proxy_mode must be one of: stream, buffer; got %q", c.Server.ProxyMode)
+	}
+	if c.Server.BufferMaxBytes < 0 {
+		return fmt.Errorf("server.buffer_max_bytes must be non-negative; got %d", c.Server.BufferMaxBytes)
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.IdleTimeout < 0 {
+		return fmt.Errorf("server.read_timeout, server.read_header_timeout and server.idle_timeout must be non-negative")
+	}
+	if err := checkDuration("upstream.timeout", c.Upstream.Timeout, "upstream.timeout_seconds", c.Upstream.TimeoutSeconds); err != nil {
+		return err
+	}
+	if err := checkDuration("upstream.response_header_timeout", c.Upstream.ResponseHeaderTimeout,
+		"upstream.response_header_timeout_seconds", c.Upstream.ResponseHeaderTimeoutSeconds); err != nil {
+		return err
+	}
+	if err := checkDuration("upstream.idle_conn_timeout", c.Upstream.IdleConnTimeout,
+		"upstream.idle_conn_timeout_seconds", c.Upstream.IdleConnTimeoutSeconds); err != nil {
+		return err
+	}
+	if err := checkDuration("upstream.queue_timeout", c.Upstream.QueueTimeout, "upstream.queue_timeout_ms", c.Upstream.QueueTimeoutMs); err != nil {
+		return err
+	}
+	if err := checkDuration("upstream.slow_threshold", c.Upstream.SlowThreshold, "upstream.slow_threshold_ms", c.Upstream.SlowThresholdMs); err != nil {
+		return err
+	}
+	if w := c.Upstream.SuccessWindow; w != 0 && w < Duration(time.Second) {
+		return fmt.Errorf("upstream.success_window must be at least 1s; got %s", w.Std())
 	}
 	if c.Upstream.IdleConnections < 0 {
 		return fmt.Errorf("upstream.idle_connections must be non-negative; got %d", c.Upstream.IdleConnections)
 	}
+	for prefix, d := range c.Upstream.PathTimeouts {
+		if prefix == "" || prefix[0] != '/' {
+			return fmt.Errorf("upstream.path_timeouts keys must start with '/'; got %q", prefix)
+		}
+		if d < 0 {
+			return fmt.Errorf("upstream.path_timeouts[%q] must be non-negative; got %s", prefix, d.Std())
+		}
+	}
+	if c.Upstream.MaxHeaderBytes < 0 {
+		return fmt.Errorf("upstream.max_header_bytes must be non-negative; got %d", c.Upstream.MaxHeaderBytes)
+	}
+	if c.Upstream.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("upstream.max_concurrent_requests must be non-negative; got %d", c.Upstream.MaxConcurrentRequests)
+	}
+	if c.Upstream.MaxQueuedRequests < 0 {
+		return fmt.Errorf("upstream.max_queued_requests must be non-negative; got %d", c.Upstream.MaxQueuedRequests)
+	}
+	// Above 90% a jittered timeout could approach zero, which the transport
+	// treats as "no timeout".
+	if j := c.Upstream.IdleTimeoutJitterPercent; j < 0 || j > 90 {
+		return fmt.Errorf("upstream.idle_timeout_jitter_percent must be between 0 and 90; got %d", j)
+	}
 	if c.Server.RateLimit.Enabled && c.Server.RateLimit.RequestsPerSecond <= 0 {
 		return fmt.Errorf("server.rate_limit.requests_per_second must be > 0 when rate limiting is enabled; got %v", c.Server.RateLimit.RequestsPerSecond)
 	}
+	if rpm := c.Server.RateLimit.RequestsPerMinute; rpm != 0 && c.Server.RateLimit.Enabled {
+		rps := c.Server.RateLimit.RequestsPerSecond
+		if rpm < rps {
+			return fmt.Errorf("server.rate_limit.requests_per_minute (%v) must be at least requests_per_second (%v)", rpm, rps)
+		}
+		if rpm >= rps*60 {
+			return fmt.Errorf("server.rate_limit.requests_per_minute (%v) has no effect unless it is below 60 × requests_per_second (%v)", rpm, rps*60)
+		}
+	}
+	for _, p := range c.Server.RateLimit.ExemptPaths {
+		if p == "" || p[0] != '/' {
+			return fmt.Errorf("server.rate_limit.exempt_paths entries must start with '/'; got %q", p)
+		}
+	}
+	switch c.Server.RateLimit.Algorithm {
+	case "token_bucket", "sliding_window", "":
+		// valid
+	default:
+		return fmt.Errorf("server.rate_limit.algorithm must be one of: token_bucket, sliding_window; got %q", c.Server.RateLimit.Algorithm)
+	}
+
+	if code := c.Server.UnavailableResponse.StatusCode; code != 0 && (code < 400 || code > 599) {
+		return fmt.Errorf("server.unavailable_response.status_code must be between 400 and 599; got %d", code)
+	}
+	if body := c.Server.UnavailableResponse.Body; body != "" && !json.Valid([]byte(strings.ReplaceAll(body, "{request_id}", "x"))) {
+		return fmt.Errorf("server.unavailable_response.body must be valid JSON")
+	}
+
+	if r := c.Log.RequestSampleRate(); !(r >= 0 && r <= 1) {
+		return fmt.Errorf("log.sample_rate must be between 0 and 1; got %v", r)
+	}
+	for _, name := range c.Log.RedactQueryParams {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("log.redact_query_params must not contain empty parameter names")
+		}
+	}
+
+	for _, prefix := range c.Upstream.ForwardHeaderPrefixes {
+		if strings.TrimSpace(prefix) == "" {
+			return fmt.Errorf("upstream.forward_header_prefixes must not contain empty prefixes")
+		}
+	}
+
+	for path, params := range c.Server.RequiredParams {
+		if path == "" || path[0] != '/' {
+			return fmt.Errorf("server.required_params paths must start with '/'; got %q", path)
+		}
+		for _, p := range params {
+			switch strings.ToLower(p) {
+			case "":
+				return fmt.Errorf("server.required_params[%q] must not contain empty parameter names", path)
+			case "apikey", "api_key":
+				return fmt.Errorf("server.required_params[%q] must not require %q; API keys are handled separately", path, p)
+			}
+		}
+	}
+
+	// Default query params must not smuggle API keys into upstream URLs.
+	for name := range c.Upstream.DefaultQueryParams {
+		switch strings.ToLower(name) {
+		case "":
+			return fmt.Errorf("upstream.default_query_params must not contain empty parameter names")
+		case "apikey", "api_key":
+			return fmt.Errorf("upstream.default_query_params must not set %q; configure vulners.api_key instead", name)
+		}
+	}
+
+	// Response header overrides.
+	for _, name := range c.Server.StripResponseHeaders {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("server.strip_response_headers must not contain empty header names")
+		}
+	}
+	for key, v := range map[string]*string{
+		"frame_options":        c.Server.SecurityHeaders.FrameOptions,
+		"content_type_options": c.Server.SecurityHeaders.ContentTypeOptions,
+	} {
+		if v := valueOr(v, ""); strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("server.security_headers.%s must not contain line breaks; got %q", key, v)
+		}
+	}
+	for name := range c.Server.SetResponseHeaders {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("server.set_response_headers must not contain empty header names")
+		}
+		// Framing headers describe the body actually sent; a fixed value
+		// would go stale as soon as a response is transformed.
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length", "transfer-encoding":
+			return fmt.Errorf("server.set_response_headers must not set %q; it is computed per response", name)
+		}
+	}
+	if strings.ContainsAny(c.Upstream.DefaultAccept, "\r\n") {
+		return fmt.Errorf("upstream.default_accept must not contain line breaks")
+	}
+	if strings.ContainsAny(c.Upstream.ForceAcceptEncoding, "\r\n") {
+		return fmt.Errorf("upstream.force_accept_encoding must not contain line breaks")
+	}
+
+	// Response transform (only when enabled).
+	if rt := c.ResponseTransform; rt.Enabled {
+		if len(rt.Paths) == 0 {
+			return fmt.Errorf("response_transform.paths must list at least one path when the transform is enabled")
+		}
+		for _, p := range rt.Paths {
+			if p == "" || p[0] != '/' {
+				return fmt.Errorf("response_transform.paths entries must start with '/'; got %q", p)
+			}
+		}
+		if len(rt.StripFields) == 0 {
+			return fmt.Errorf("response_transform.strip_fields must list at least one field when the transform is enabled")
+		}
+		if rt.MaxBodyBytes < 0 {
+			return fmt.Errorf("response_transform.max_body_bytes must be non-negative; got %d", rt.MaxBodyBytes)
+		}
+	}
 
 	// Log fields.
 	level := strings.ToLower(c.Log.Level)
@@ -173,6 +921,62 @@ func (c *Config) validate() error {
 	default:
 		return fmt.Errorf("log.format must be one of: json, text; got %q", c.Log.Format)
 	}
+	switch c.Log.Output {
+	case "stdout", "stderr", "":
+		// valid
+	case "file":
+		if strings.TrimSpace(c.Log.FilePath) == "" {
+			return fmt.Errorf("log.file_path is required when log.output is \"file\"")
+		}
+	default:
+		return fmt.Errorf("log.output must be one of: stdout, stderr, file; got %q", c.Log.Output)
+	}
+	if c.Log.MaxSizeMB < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAgeDays < 0 {
+		return fmt.Errorf("log.max_size_mb, log.max_backups and log.max_age_days must be non-negative")
+	}
+	if c.Log.StreamProgressInterval < 0 {
+		return fmt.Errorf("log.stream_progress_interval must be non-negative; got %s", c.Log.StreamProgressInterval.Std())
+	}
+	if c.Log.AuditEnabled && c.Log.AuditOutput != "" && strings.TrimSpace(c.Log.AuditOutput) == "" {
+		return fmt.Errorf("log.audit_output must not be blank")
+	}
+
+	if err := checkDuration("startup.self_test_timeout", c.Startup.SelfTestTimeout,
+		"startup.self_test_timeout_seconds", c.Startup.SelfTestTimeoutSeconds); err != nil {
+		return err
+	}
+	if p := c.Startup.SelfTestPath; p != "" && p[0] != '/' {
+		return fmt.Errorf("startup.self_test_path must start with '/'; got %q", p)
+	}
+
+	if c.Maintenance.RetryAfter < 0 {
+		return fmt.Errorf("maintenance.retry_after must be non-negative; got %s", c.Maintenance.RetryAfter.Std())
+	}
+	if c.Admin.Token != "" && c.Maintenance.AdminToken != "" {
+		return fmt.Errorf("set only one of admin.token and maintenance.admin_token")
+	}
+	if t := c.AdminToken(); t != "" && len(t) < 16 {
+		return fmt.Errorf("admin.token must be at least 16 characters")
+	}
+
+	if err := checkDuration("liveness.heartbeat_interval", c.Liveness.HeartbeatInterval,
+		"liveness.heartbeat_interval_seconds", c.Liveness.HeartbeatIntervalSeconds); err != nil {
+		return err
+	}
+	if err := checkDuration("liveness.stall_threshold", c.Liveness.StallThreshold,
+		"liveness.stall_threshold_seconds", c.Liveness.StallThresholdSeconds); err != nil {
+		return err
+	}
+	if c.StatusCheck.Timeout < 0 {
+		return fmt.Errorf("status_check.timeout must be non-negative; got %s", c.StatusCheck.Timeout.Std())
+	}
+	if c.StatusCheck.MinInterval < 0 {
+		return fmt.Errorf("status_check.min_interval must be non-negative; got %s", c.StatusCheck.MinInterval.Std())
+	}
+
+	if err := checkDuration("debug.inject_latency", c.Debug.InjectLatency, "debug.inject_latency_ms", c.Debug.InjectLatencyMs); err != nil {
+		return err
+	}
 
 	// Metrics path validation (only when metrics are enabled).
 	if c.Metrics.Enabled && c.Metrics.Path != "" {
@@ -180,7 +984,7 @@ func (c *Config) validate() error {
 		if p[0] != '/' {
 			return fmt.Errorf("metrics.path must start with '/'; got %q", p)
 		}
-		for _, reserved := range []string{"/api/v3", "/api/v4", "/healthz", "/proxy/status"} {
+		for _, reserved := range routes.ReservedPrefixes() {
 			if p == reserved || strings.HasPrefix(p, reserved+"/") {
 				return fmt.Errorf("metrics.path %q conflicts with reserved route %q", p, reserved)
 			}
@@ -190,6 +994,40 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// checkBaseURL validates an upstream origin: HTTPS, no query string or
+// fragment, and no dot segments in its path.
+func checkBaseURL(key, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid URL: %w", key, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%s must use HTTPS; got %q", key, raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.ForceQuery {
+		return fmt.Errorf("%s must not have a query string or fragment; got %q", key, raw)
+	}
+	for seg := range strings.SplitSeq(u.Path, "/") {
+		if seg == "." || seg == ".." {
+			return fmt.Errorf("%s path must not contain dot segments; got %q", key, raw)
+		}
+	}
+	return nil
+}
+
+// validateResolved checks constraints that depend on values setDefaults
+// resolves from legacy keys and defaults, so it runs after setDefaults.
+func (c *Config) validateResolved() error {
+	if d := c.Upstream.Timeout.Std(); c.Upstream.StrictTimeouts && d < MinUpstreamTimeout {
+		return fmt.Errorf("upstream.timeout must be at least %s when upstream.strict_timeouts is set; got %s", MinUpstreamTimeout, d)
+	}
+	if l := c.Liveness; l.Enabled && l.StallThreshold <= l.HeartbeatInterval {
+		return fmt.Errorf("liveness.stall_threshold must be greater than liveness.heartbeat_interval; got %s and %s",
+			l.StallThreshold.Std(), l.HeartbeatInterval.Std())
+	}
+	return nil
+}
+
 // setDefaults fills zero-valued fields with sensible defaults.
 // For integer fields (Port, BodyMaxBytes, etc.), zero means "unset" because TOML
 // cannot distinguish between an explicit 0 and an omitted key. Setting port=0 in
@@ -204,21 +1042,145 @@ func (c *Config) setDefaults() {
 	if c.Server.BodyMaxBytes == 0 {
 		c.Server.BodyMaxBytes = 10 * 1024 * 1024 // 10 MB
 	}
-	if c.Upstream.TimeoutSeconds == 0 {
-		c.Upstream.TimeoutSeconds = 120
+	if c.Vulners.APIKeyHeader == "" {
+		c.Vulners.APIKeyHeader = UpstreamAPIKeyHeader
+	}
+	if c.Vulners.KeyPrecedence == "" {
+		c.Vulners.KeyPrecedence = "config"
+	}
+	if c.Vulners.TenantHeader == "" {
+		c.Vulners.TenantHeader = "X-Tenant-Id"
+	}
+	if c.Server.RateLimit.ExemptPaths == nil {
+		c.Server.RateLimit.ExemptPaths = []string{routes.Healthz, routes.Status}
+	}
+	if c.Server.ProxyMode == "" {
+		c.Server.ProxyMode = "stream"
+	}
+	if c.Server.BufferMaxBytes == 0 {
+		c.Server.BufferMaxBytes = 1024 * 1024 // 1 MB
+	}
+	if c.Server.RateLimit.Algorithm == "" {
+		c.Server.RateLimit.Algorithm = "token_bucket"
+	}
+	if c.Server.ReadTimeout == 0 {
+		c.Server.ReadTimeout = Duration(30 * time.Second)
+	}
+	if c.Server.ReadHeaderTimeout == 0 {
+		c.Server.ReadHeaderTimeout = Duration(10 * time.Second)
+	}
+	if c.Server.CorrelationIDHeader == "" {
+		c.Server.CorrelationIDHeader = "X-Correlation-Id"
+	}
+	if c.Server.ShedRetryAfter == 0 {
+		c.Server.ShedRetryAfter = Duration(time.Second)
+	}
+	if c.Server.MaxPathLength == 0 {
+		c.Server.MaxPathLength = 8192
+	}
+	if c.Server.MaxQueryParams == 0 {
+		c.Server.MaxQueryParams = 100
+	}
+	if c.Server.IdleTimeout == 0 {
+		c.Server.IdleTimeout = Duration(120 * time.Second)
+	}
+	c.Server.TCPKeepAlive = fromLegacy(c.Server.TCPKeepAlive, c.Server.TCPKeepAliveSeconds, time.Second)
+	if c.Server.TCPKeepAlive == 0 {
+		c.Server.TCPKeepAlive = Duration(30 * time.Second)
+	}
+
+	c.Upstream.Timeout = fromLegacy(c.Upstream.Timeout, c.Upstream.TimeoutSeconds, time.Second)
+	c.Upstream.ResponseHeaderTimeout = fromLegacy(c.Upstream.ResponseHeaderTimeout, c.Upstream.ResponseHeaderTimeoutSeconds, time.Second)
+	c.Upstream.IdleConnTimeout = fromLegacy(c.Upstream.IdleConnTimeout, c.Upstream.IdleConnTimeoutSeconds, time.Second)
+	c.Upstream.QueueTimeout = fromLegacy(c.Upstream.QueueTimeout, c.Upstream.QueueTimeoutMs, time.Millisecond)
+	c.Upstream.SlowThreshold = fromLegacy(c.Upstream.SlowThreshold, c.Upstream.SlowThresholdMs, time.Millisecond)
+	c.Startup.SelfTestTimeout = fromLegacy(c.Startup.SelfTestTimeout, c.Startup.SelfTestTimeoutSeconds, time.Second)
+	c.Debug.InjectLatency = fromLegacy(c.Debug.InjectLatency, c.Debug.InjectLatencyMs, time.Millisecond)
+	c.Liveness.HeartbeatInterval = fromLegacy(c.Liveness.HeartbeatInterval, c.Liveness.HeartbeatIntervalSeconds, time.Second)
+	c.Liveness.StallThreshold = fromLegacy(c.Liveness.StallThreshold, c.Liveness.StallThresholdSeconds, time.Second)
+
+	if c.Liveness.HeartbeatInterval == 0 {
+		c.Liveness.HeartbeatInterval = Duration(defaultHeartbeatInterval)
+	}
+	if c.Liveness.StallThreshold == 0 {
+		c.Liveness.StallThreshold = Duration(defaultStallThreshold)
+	}
+
+	if c.Upstream.Timeout == 0 {
+		c.Upstream.Timeout = Duration(120 * time.Second)
+	}
+	if c.Upstream.ResponseHeaderTimeout == 0 {
+		c.Upstream.ResponseHeaderTimeout = c.Upstream.Timeout
+		for _, d := range c.Upstream.PathTimeouts {
+			c.Upstream.ResponseHeaderTimeout = max(c.Upstream.ResponseHeaderTimeout, d)
+		}
+	}
+	if c.Upstream.IdleConnTimeout == 0 {
+		c.Upstream.IdleConnTimeout = Duration(90 * time.Second)
+	}
+	if c.Upstream.SuccessWindow == 0 {
+		c.Upstream.SuccessWindow = Duration(time.Minute)
 	}
 	if c.Upstream.IdleConnections == 0 {
 		c.Upstream.IdleConnections = 100
 	}
+	if len(c.Upstream.ForwardHeaderPrefixes) == 0 {
+		c.Upstream.ForwardHeaderPrefixes = []string{"x-vulners-"}
+	}
+	if c.Upstream.OnRedirect == "" {
+		c.Upstream.OnRedirect = "error"
+	}
+	if c.Upstream.DefaultAccept == "" {
+		c.Upstream.DefaultAccept = "application/json"
+	}
+	if c.Upstream.QueueTimeout == 0 {
+		c.Upstream.QueueTimeout = Duration(time.Second)
+	}
+	if c.Shadow.Timeout == 0 {
+		c.Shadow.Timeout = Duration(30 * time.Second)
+	}
+	if c.Shadow.MaxInFlight == 0 {
+		c.Shadow.MaxInFlight = 100
+	}
 	if c.Log.Level == "" {
 		c.Log.Level = "info"
 	}
 	if c.Log.Format == "" {
 		c.Log.Format = "json"
 	}
+	if c.Log.Output == "" {
+		c.Log.Output = "stdout"
+	}
+	if c.Log.MaxSizeMB == 0 {
+		c.Log.MaxSizeMB = 100
+	}
+	if c.Log.AuditOutput == "" {
+		c.Log.AuditOutput = "stdout"
+	}
 	if c.Metrics.Path == "" {
 		c.Metrics.Path = "/metrics"
 	}
+	if c.Startup.SelfTestPath == "" {
+		c.Startup.SelfTestPath = "/api/v3/apiKey/valid/"
+	}
+	if c.Startup.SelfTestTimeout == 0 {
+		c.Startup.SelfTestTimeout = Duration(10 * time.Second)
+	}
+	if c.StatusCheck.Timeout == 0 {
+		c.StatusCheck.Timeout = Duration(5 * time.Second)
+	}
+	if c.StatusCheck.MinInterval == 0 {
+		c.StatusCheck.MinInterval = Duration(10 * time.Second)
+	}
+	if c.Maintenance.Message == "" {
+		c.Maintenance.Message = "the Vulners API is under maintenance; retry later"
+	}
+	if c.Maintenance.RetryAfter == 0 {
+		c.Maintenance.RetryAfter = Duration(5 * time.Minute)
+	}
+	if c.ResponseTransform.MaxBodyBytes == 0 {
+		c.ResponseTransform.MaxBodyBytes = 1024 * 1024 // 1 MB
+	}
 }
 
 // findConfig returns the first config path that exists, or empty string.
@@ -236,11 +1198,77 @@ func findConfigInPaths(paths []string) string {
 	return ""
 }
 
+// AdminToken returns the bearer token that guards the admin endpoints:
+// admin.token, or the legacy maintenance.admin_token when it is unset. ""
+// means no token is configured.
+func (c *Config) AdminToken() string {
+	if c.Admin.Token != "" {
+		return c.Admin.Token
+	}
+	return c.Maintenance.AdminToken
+}
+
+// FilePath returns the path of the config file the configuration was loaded
+// from, or "" when it was not loaded from a file.
+func (c *Config) FilePath() string {
+	return c.filePath
+}
+
 // Addr returns the server listen address as host:port.
 func (c *ServerConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// valueOr returns *p, or def when p is nil.
+//
+// Most keys follow the zero-means-default rule of setDefaults. A few need a
+// non-zero default while their zero value is itself a useful setting:
+// expose_version = false, an empty security header, log.sample_rate = 0.
+// Those are pointers, so that an absent key can be told apart from a zero,
+// and are read only through an accessor built on valueOr.
+func valueOr[T any](p *T, def T) T {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+// VersionExposed reports whether responses carry X-Proxy-Version. It is true
+// unless expose_version is explicitly set to false.
+func (c *ServerConfig) VersionExposed() bool {
+	return valueOr(c.ExposeVersion, true)
+}
+
+// Headers returns the security headers to set on responses, keyed by header
+// name, with defaults applied and disabled headers left out.
+func (c *SecurityHeadersConfig) Headers() map[string]string {
+	headers := make(map[string]string, 2)
+	for name, h := range map[string]struct {
+		value *string
+		def   string
+	}{
+		"X-Frame-Options":        {c.FrameOptions, "DENY"},
+		"X-Content-Type-Options": {c.ContentTypeOptions, "nosniff"},
+	} {
+		if v := strings.TrimSpace(valueOr(h.value, h.def)); v != "" {
+			headers[name] = v
+		}
+	}
+	return headers
+}
+
+// WarnTimeouts logs a warning if upstream.timeout is below
+// MinUpstreamTimeout. With upstream.strict_timeouts, Load rejects such a
+// value instead.
+func (c *Config) WarnTimeouts(logger *slog.Logger) {
+	if d := c.Upstream.Timeout.Std(); d > 0 && d < MinUpstreamTimeout {
+		logger.Warn("upstream.timeout is unusually low; most Vulners requests will time out",
+			"timeout", d,
+			"recommended_min", MinUpstreamTimeout,
+		)
+	}
+}
+
 // WarnPermissions logs a warning if the config file is readable by group or others.
 func (c *Config) WarnPermissions(logger *slog.Logger) {
 	if c.filePath == "" {
@@ -2,12 +2,15 @@ package config
 
 import (
 	"bytes"
+	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // cliWithPath returns a CLI struct pointing at the given config file.
@@ -23,6 +26,7 @@ func TestLoad_ValidConfig(t *testing.T) {
 host = "127.0.0.1"
 port = 9000
 body_max_bytes = 5242880
+enable_h2c = true
 
 [vulners]
 api_key = "test-key-12345"
@@ -51,12 +55,18 @@ format = "text"
 	if cfg.Server.Port != 9000 {
 		t.Errorf("Server.Port = %d, want %d", cfg.Server.Port, 9000)
 	}
+	if !cfg.Server.EnableH2C {
+		t.Error("expected Server.EnableH2C = true")
+	}
 	if cfg.Vulners.APIKey != "test-key-12345" {
 		t.Errorf("Vulners.APIKey = %q, want %q", cfg.Vulners.APIKey, "test-key-12345")
 	}
 	if cfg.Upstream.TimeoutSeconds != 60 {
 		t.Errorf("Upstream.TimeoutSeconds = %d, want %d", cfg.Upstream.TimeoutSeconds, 60)
 	}
+	if cfg.Upstream.Timeout.Std() != 60*time.Second {
+		t.Errorf("Upstream.Timeout = %v, want %v", cfg.Upstream.Timeout.Std(), 60*time.Second)
+	}
 	if cfg.Log.Level != "debug" {
 		t.Errorf("Log.Level = %q, want %q", cfg.Log.Level, "debug")
 	}
@@ -108,6 +118,70 @@ base_url = "https://vulners.com"
 	}
 }
 
+func TestLoad_SecondaryAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		vulners string
+		wantErr bool
+	}{
+		{"primary and secondary", "api_key = \"old-key-12345\"\nsecondary_api_key = \"new-key-12345\"", false},
+		{"secondary without primary", "secondary_api_key = \"new-key-12345\"", true},
+		{"secondary equals primary", "api_key = \"same-key-12345\"\nsecondary_api_key = \"same-key-12345\"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[vulners]\n" + tt.vulners + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_APIKeyHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		entry      string
+		wantHeader string
+		wantErr    bool
+	}{
+		{"default", ``, "X-Api-Key", false},
+		{"custom", `api_key_header = "X-Vulners-Tenant-Key"`, "X-Vulners-Tenant-Key", false},
+		{"contains space", `api_key_header = "X Api Key"`, "", true},
+		{"contains colon", `api_key_header = "X-Api-Key:"`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[vulners]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Vulners.APIKeyHeader != tt.wantHeader {
+				t.Errorf("Vulners.APIKeyHeader = %q, want %q", cfg.Vulners.APIKeyHeader, tt.wantHeader)
+			}
+		})
+	}
+}
+
 func TestLoad_InvalidLogLevel(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
@@ -165,6 +239,27 @@ base_url = "https://vulners.com"
 	if cfg.Log.Format != "json" {
 		t.Errorf("default Log.Format = %q, want %q", cfg.Log.Format, "json")
 	}
+	if cfg.Upstream.Timeout.Std() != 120*time.Second {
+		t.Errorf("default Upstream.Timeout = %v, want %v", cfg.Upstream.Timeout.Std(), 120*time.Second)
+	}
+	if cfg.Upstream.ResponseHeaderTimeout != cfg.Upstream.Timeout {
+		t.Errorf("default Upstream.ResponseHeaderTimeout = %v, want Timeout (%v)",
+			cfg.Upstream.ResponseHeaderTimeout.Std(), cfg.Upstream.Timeout.Std())
+	}
+	if cfg.Server.ReadTimeout.Std() != 30*time.Second || cfg.Server.ReadHeaderTimeout.Std() != 10*time.Second ||
+		cfg.Server.IdleTimeout.Std() != 120*time.Second {
+		t.Errorf("default server timeouts = %v/%v/%v, want 30s/10s/2m0s",
+			cfg.Server.ReadTimeout.Std(), cfg.Server.ReadHeaderTimeout.Std(), cfg.Server.IdleTimeout.Std())
+	}
+	if len(cfg.Upstream.ForwardHeaderPrefixes) != 1 || cfg.Upstream.ForwardHeaderPrefixes[0] != "x-vulners-" {
+		t.Errorf("default Upstream.ForwardHeaderPrefixes = %v, want [x-vulners-]", cfg.Upstream.ForwardHeaderPrefixes)
+	}
+	if cfg.Upstream.DefaultAccept != "application/json" {
+		t.Errorf("default Upstream.DefaultAccept = %q, want %q", cfg.Upstream.DefaultAccept, "application/json")
+	}
+	if cfg.Startup.SelfTestPath != "/api/v3/apiKey/valid/" || cfg.Startup.SelfTestTimeout.Std() != 10*time.Second {
+		t.Errorf("default Startup = %+v, want self_test_path /api/v3/apiKey/valid/ and 10s timeout", cfg.Startup)
+	}
 }
 
 func TestLoad_MissingFile(t *testing.T) {
@@ -199,7 +294,7 @@ level = "info"
 		Config:   path,
 		Host:     "127.0.0.1",
 		Port:     3000,
-		APIKey:   "cli-key",
+		APIKey:   "cli-key-12345",
 		LogLevel: "debug",
 	}
 
@@ -214,14 +309,91 @@ level = "info"
 	if cfg.Server.Port != 3000 {
 		t.Errorf("Server.Port = %d, want %d (CLI override)", cfg.Server.Port, 3000)
 	}
-	if cfg.Vulners.APIKey != "cli-key" {
-		t.Errorf("Vulners.APIKey = %q, want %q (CLI override)", cfg.Vulners.APIKey, "cli-key")
+	if cfg.Vulners.APIKey != "cli-key-12345" {
+		t.Errorf("Vulners.APIKey = %q, want %q (CLI override)", cfg.Vulners.APIKey, "cli-key-12345")
 	}
 	if cfg.Log.Level != "debug" {
 		t.Errorf("Log.Level = %q, want %q (CLI override)", cfg.Log.Level, "debug")
 	}
 }
 
+func TestLoad_DurationStrings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[server]
+read_timeout = "45s"
+
+[upstream]
+base_url = "https://vulners.com"
+timeout = "2m"
+queue_timeout = "250ms"
+response_header_timeout_seconds = 30
+slow_threshold_ms = 1500
+
+[startup]
+self_test_timeout = "5s"
+
+[debug]
+inject_latency_ms = 250
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  Duration
+		want time.Duration
+	}{
+		{"server.read_timeout", cfg.Server.ReadTimeout, 45 * time.Second},
+		{"upstream.timeout", cfg.Upstream.Timeout, 2 * time.Minute},
+		{"upstream.queue_timeout", cfg.Upstream.QueueTimeout, 250 * time.Millisecond},
+		{"upstream.response_header_timeout from legacy seconds", cfg.Upstream.ResponseHeaderTimeout, 30 * time.Second},
+		{"upstream.slow_threshold from legacy milliseconds", cfg.Upstream.SlowThreshold, 1500 * time.Millisecond},
+		{"startup.self_test_timeout", cfg.Startup.SelfTestTimeout, 5 * time.Second},
+		{"debug.inject_latency from legacy milliseconds", cfg.Debug.InjectLatency, 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if tt.got.Std() != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got.Std(), tt.want)
+		}
+	}
+}
+
+func TestLoad_DurationStrings_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"negative", `timeout = "-5s"`},
+		{"unparseable", `timeout = "two minutes"`},
+		{"missing unit", `timeout = "120"`},
+		{"both forms set", "timeout = \"2m\"\ntimeout_seconds = 120"},
+		{"negative slow threshold", `slow_threshold_ms = -1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(cliWithPath(path)); err == nil {
+				t.Error("Load() error = nil, want error")
+			}
+		})
+	}
+}
+
 func TestLoad_HTTPUpstreamRejected(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
@@ -242,6 +414,38 @@ base_url = "http://vulners.com"
 	}
 }
 
+func TestLoad_UpstreamBasePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		wantErr bool
+	}{
+		{"no path", "https://vulners.com", false},
+		{"path", "https://vulners.com/api", false},
+		{"path with trailing slash", "https://vulners.com/gateway/", false},
+		{"query string", "https://vulners.com/api?x=1", true},
+		{"empty query string", "https://vulners.com/api?", true},
+		{"fragment", "https://vulners.com/api#top", true},
+		{"dot segment", "https://vulners.com/api/../v3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := fmt.Sprintf("[upstream]\nbase_url = %q\n", tt.baseURL)
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestLoad_NegativePort(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
@@ -325,6 +529,97 @@ requests_per_second = 50.0
 	if cfg.Server.RateLimit.RequestsPerSecond != 50.0 {
 		t.Errorf("RateLimit.RequestsPerSecond = %v, want 50.0", cfg.Server.RateLimit.RequestsPerSecond)
 	}
+	if cfg.Server.RateLimit.Algorithm != "token_bucket" {
+		t.Errorf("RateLimit.Algorithm = %q, want default %q", cfg.Server.RateLimit.Algorithm, "token_bucket")
+	}
+	if got := cfg.Server.RateLimit.ExemptPaths; len(got) != 2 || got[0] != "/healthz" || got[1] != "/proxy/status" {
+		t.Errorf("RateLimit.ExemptPaths = %v, want default [/healthz /proxy/status]", got)
+	}
+}
+
+func TestLoad_RateLimitConfig_ExemptPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    int
+		wantErr bool
+	}{
+		{"explicitly empty", `exempt_paths = []`, 0, false},
+		{"custom", `exempt_paths = ["/internal/"]`, 1, false},
+		{"relative path", `exempt_paths = ["internal"]`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[server.rate_limit]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(cfg.Server.RateLimit.ExemptPaths) != tt.want {
+				t.Errorf("RateLimit.ExemptPaths = %v, want %d entries", cfg.Server.RateLimit.ExemptPaths, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_RateLimitConfig_BadAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[server.rate_limit]
+enabled = true
+requests_per_second = 10
+algorithm = "leaky_bucket"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(cliWithPath(path)); err == nil {
+		t.Fatal("Load() expected error for unknown rate limit algorithm, got nil")
+	}
+}
+
+func TestLoad_RateLimitConfig_RequestsPerMinute(t *testing.T) {
+	tests := []struct {
+		name    string
+		rps     float64
+		rpm     float64
+		wantErr bool
+	}{
+		{"unset", 100, 0, false},
+		{"valid", 100, 600, false},
+		{"below per-second", 100, 50, true},
+		{"negative", 100, -1, true},
+		{"no effect", 10, 600, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := fmt.Sprintf("[upstream]\nbase_url = \"https://vulners.com\"\n\n[server.rate_limit]\n"+
+				"enabled = true\nrequests_per_second = %v\nrequests_per_minute = %v\n", tt.rps, tt.rpm)
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
 }
 
 func TestLoad_RateLimitConfig_Disabled(t *testing.T) {
@@ -371,116 +666,132 @@ requests_per_second = 0
 	}
 }
 
-func TestWarnPermissions_Loose(t *testing.T) {
-	if runtime.GOOS == "windows" {
-		t.Skip("permission bits not meaningful on Windows")
-	}
-	dir := t.TempDir()
-	path := filepath.Join(dir, "config.toml")
-	if err := os.WriteFile(path, []byte("# test"), 0o644); err != nil {
-		t.Fatal(err)
+func TestLoad_ProxyMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		wantMode string
+		wantErr  bool
+	}{
+		{"default", ``, "stream", false},
+		{"buffer", `proxy_mode = "buffer"`, "buffer", false},
+		{"unknown mode", `proxy_mode = "cache"`, "", true},
+		{"negative buffer size", `buffer_max_bytes = -1`, "", true},
 	}
 
-	cfg := &Config{filePath: path}
-	var buf bytes.Buffer
-	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
-	cfg.WarnPermissions(logger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[server]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
 
-	if !strings.Contains(buf.String(), "readable by group/others") {
-		t.Errorf("expected permission warning, got: %q", buf.String())
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Server.ProxyMode != tt.wantMode {
+				t.Errorf("Server.ProxyMode = %q, want %q", cfg.Server.ProxyMode, tt.wantMode)
+			}
+			if cfg.Server.BufferMaxBytes != 1024*1024 {
+				t.Errorf("Server.BufferMaxBytes = %d, want default %d", cfg.Server.BufferMaxBytes, 1024*1024)
+			}
+		})
 	}
 }
 
-func TestWarnPermissions_Strict(t *testing.T) {
-	if runtime.GOOS == "windows" {
-		t.Skip("permission bits not meaningful on Windows")
-	}
-	dir := t.TempDir()
-	path := filepath.Join(dir, "config.toml")
-	if err := os.WriteFile(path, []byte("# test"), 0o600); err != nil {
-		t.Fatal(err)
+func TestLoad_UnavailableResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"unset", ``, false},
+		{"status and body", "status_code = 503\nbody = '{\"error\":\"down\",\"request_id\":\"{request_id}\"}'", false},
+		{"status out of range", `status_code = 200`, true},
+		{"body not JSON", `body = "upstream down"`, true},
 	}
 
-	cfg := &Config{filePath: path}
-	var buf bytes.Buffer
-	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
-	cfg.WarnPermissions(logger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[server.unavailable_response]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
 
-	if buf.Len() != 0 {
-		t.Errorf("expected no warning for 0600 file, got: %q", buf.String())
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
-func TestFindConfigInPaths_Found(t *testing.T) {
+func TestLoad_ResponseHeaderOverrides(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
-	if err := os.WriteFile(path, []byte("[upstream]\nbase_url = \"https://vulners.com\"\n"), 0o644); err != nil {
-		t.Fatal(err)
-	}
+	data := `
+[server]
+strip_response_headers = ["Server", "Date"]
 
-	got := findConfigInPaths([]string{path})
-	if got != path {
-		t.Errorf("findConfigInPaths() = %q, want %q", got, path)
-	}
-}
+[server.set_response_headers]
+Cache-Control = "no-store"
 
-func TestFindConfigInPaths_NotFound(t *testing.T) {
-	got := findConfigInPaths([]string{"/nonexistent/a.toml", "/nonexistent/b.toml"})
-	if got != "" {
-		t.Errorf("findConfigInPaths() = %q, want empty", got)
+[upstream]
+base_url = "https://vulners.com"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
 	}
-}
 
-func TestFindConfigInPaths_Priority(t *testing.T) {
-	dir1 := t.TempDir()
-	dir2 := t.TempDir()
-	path1 := filepath.Join(dir1, "config.toml")
-	path2 := filepath.Join(dir2, "config.toml")
-	for _, p := range []string{path1, path2} {
-		if err := os.WriteFile(p, []byte("[upstream]\nbase_url = \"https://vulners.com\"\n"), 0o644); err != nil {
-			t.Fatal(err)
-		}
+	cfg, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
 	}
-
-	got := findConfigInPaths([]string{path1, path2})
-	if got != path1 {
-		t.Errorf("findConfigInPaths() = %q, want first match %q", got, path1)
+	if len(cfg.Server.StripResponseHeaders) != 2 {
+		t.Errorf("StripResponseHeaders = %v, want 2 entries", cfg.Server.StripResponseHeaders)
+	}
+	if v := cfg.Server.SetResponseHeaders["Cache-Control"]; v != "no-store" {
+		t.Errorf("SetResponseHeaders[Cache-Control] = %q, want %q", v, "no-store")
 	}
 }
 
-func TestLoad_MetricsPathDefault(t *testing.T) {
+func TestLoad_ResponseHeaderOverrides_EmptyName(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
 	data := `
+[server]
+strip_response_headers = [""]
+
 [upstream]
 base_url = "https://vulners.com"
-
-[metrics]
-enabled = true
 `
 	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
 		t.Fatal(err)
 	}
 
-	cfg, err := Load(cliWithPath(path))
-	if err != nil {
-		t.Fatalf("Load() error = %v", err)
-	}
-	if cfg.Metrics.Path != "/metrics" {
-		t.Errorf("Metrics.Path = %q, want %q", cfg.Metrics.Path, "/metrics")
+	_, err := Load(cliWithPath(path))
+	if err == nil {
+		t.Fatal("Load() expected error for empty strip_response_headers entry, got nil")
 	}
 }
 
-func TestLoad_MetricsPathNoLeadingSlash(t *testing.T) {
+func TestLoad_ResponseHeaderOverrides_FramingHeader(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
 	data := `
+[server.set_response_headers]
+content-length = "0"
+
 [upstream]
 base_url = "https://vulners.com"
-
-[metrics]
-enabled = true
-path = "metrics"
 `
 	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
 		t.Fatal(err)
@@ -488,62 +799,165 @@ path = "metrics"
 
 	_, err := Load(cliWithPath(path))
 	if err == nil {
-		t.Fatal("Load() expected error for metrics.path without leading slash, got nil")
-	}
-	if !strings.Contains(err.Error(), "metrics.path") {
-		t.Errorf("error = %q, want mention of metrics.path", err)
+		t.Fatal("Load() expected error for Content-Length in set_response_headers, got nil")
 	}
 }
 
-func TestLoad_MetricsPathConflictsWithAPIRoute(t *testing.T) {
+func TestLoad_PathTimeouts(t *testing.T) {
 	tests := []struct {
-		name string
-		path string
+		name              string
+		entry             string
+		wantHeaderTimeout time.Duration
+		wantErr           bool
 	}{
-		{"api/v3 exact", "/api/v3"},
-		{"api/v3 sub", "/api/v3/metrics"},
-		{"api/v4 exact", "/api/v4"},
-		{"healthz", "/healthz"},
-		{"proxy/status", "/proxy/status"},
+		{"raises default response_header_timeout", `"/api/v3/archive/" = "10m"`, 10 * time.Minute, false},
+		{"shorter keeps default", `"/api/v3/search/" = "5s"`, 2 * time.Minute, false},
+		{"relative prefix", `"api/v3/" = "5s"`, 0, true},
+		{"negative", `"/api/v3/" = "-5s"`, 0, true},
+		{"missing unit", `"/api/v3/search/" = 5`, 0, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			dir := t.TempDir()
-			cfgPath := filepath.Join(dir, "config.toml")
-			data := `
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[upstream.path_timeouts]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Upstream.ResponseHeaderTimeout.Std(); got != tt.wantHeaderTimeout {
+				t.Errorf("ResponseHeaderTimeout = %s, want %s", got, tt.wantHeaderTimeout)
+			}
+		})
+	}
+}
+
+func TestDuration_UnmarshalText_BareNumber(t *testing.T) {
+	var d Duration
+	err := d.UnmarshalText([]byte("5"))
+	if err == nil || !strings.Contains(err.Error(), `"5s"`) {
+		t.Errorf("UnmarshalText(5) error = %v, want a hint to write \"5s\"", err)
+	}
+}
+
+func TestLoad_UpstreamQueue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
 [upstream]
 base_url = "https://vulners.com"
+max_concurrent_requests = 8
+max_queued_requests = 16
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
 
-[metrics]
-enabled = true
-path = "` + tt.path + `"
+	cfg, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Upstream.MaxConcurrentRequests != 8 {
+		t.Errorf("MaxConcurrentRequests = %d, want 8", cfg.Upstream.MaxConcurrentRequests)
+	}
+	if cfg.Upstream.MaxQueuedRequests != 16 {
+		t.Errorf("MaxQueuedRequests = %d, want 16", cfg.Upstream.MaxQueuedRequests)
+	}
+	if cfg.Upstream.QueueTimeout.Std() != time.Second {
+		t.Errorf("QueueTimeout = %v, want default 1s", cfg.Upstream.QueueTimeout.Std())
+	}
+}
+
+func TestLoad_UpstreamQueue_Negative(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+queue_timeout_ms = -1
 `
-			if err := os.WriteFile(cfgPath, []byte(data), 0o644); err != nil {
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(cliWithPath(path))
+	if err == nil {
+		t.Fatal("Load() expected error for negative queue_timeout_ms, got nil")
+	}
+}
+
+func TestLoad_IdleTimeoutJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   int
+		wantErr bool
+	}{
+		{"disabled", 0, false},
+		{"valid", 20, false},
+		{"max", 90, false},
+		{"negative", -1, true},
+		{"too large", 91, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := fmt.Sprintf("[upstream]\nbase_url = \"https://vulners.com\"\nidle_timeout_jitter_percent = %d\n", tt.value)
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
 				t.Fatal(err)
 			}
 
-			_, err := Load(cliWithPath(cfgPath))
-			if err == nil {
-				t.Fatalf("Load() expected error for metrics.path=%q conflicting with route, got nil", tt.path)
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !strings.Contains(err.Error(), "conflicts") {
-				t.Errorf("error = %q, want mention of conflict", err)
+		})
+	}
+}
+
+func TestLoad_StartupSelfTest_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"relative path", `self_test_path = "api/v3/apiKey/valid/"`},
+		{"negative timeout", `self_test_timeout_seconds = -1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[startup]\nself_test = true\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(cliWithPath(path)); err == nil {
+				t.Error("Load() error = nil, want validation error")
 			}
 		})
 	}
 }
 
-func TestLoad_MetricsPathValid(t *testing.T) {
+func TestLoad_DefaultQueryParams(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
 	data := `
 [upstream]
 base_url = "https://vulners.com"
 
-[metrics]
-enabled = true
-path = "/custom-metrics"
+[upstream.default_query_params]
+size = "20"
 `
 	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
 		t.Fatal(err)
@@ -553,36 +967,1158 @@ path = "/custom-metrics"
 	if err != nil {
 		t.Fatalf("Load() error = %v", err)
 	}
-	if cfg.Metrics.Path != "/custom-metrics" {
-		t.Errorf("Metrics.Path = %q, want %q", cfg.Metrics.Path, "/custom-metrics")
+	if v := cfg.Upstream.DefaultQueryParams["size"]; v != "20" {
+		t.Errorf("DefaultQueryParams[size] = %q, want %q", v, "20")
 	}
 }
 
-func TestLoad_MetricsDisabledSkipsPathValidation(t *testing.T) {
+func TestLoad_DefaultQueryParams_RejectsAPIKey(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.toml")
 	data := `
 [upstream]
 base_url = "https://vulners.com"
 
-[metrics]
-enabled = false
-path = "bad-no-slash"
+[upstream.default_query_params]
+apiKey = "secret"
 `
 	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
 		t.Fatal(err)
 	}
 
 	_, err := Load(cliWithPath(path))
+	if err == nil {
+		t.Fatal("Load() expected error for apiKey in default_query_params, got nil")
+	}
+}
+
+func TestLoad_ResponseTransform(t *testing.T) {
+	tests := []struct {
+		name    string
+		section string
+		wantErr bool
+	}{
+		{"disabled needs nothing", "enabled = false", false},
+		{"valid", "enabled = true\npaths = [\"/api/v3/search/\"]\nstrip_fields = [\"highlight\"]", false},
+		{"no paths", "enabled = true\nstrip_fields = [\"highlight\"]", true},
+		{"relative path", "enabled = true\npaths = [\"api\"]\nstrip_fields = [\"highlight\"]", true},
+		{"no fields", "enabled = true\npaths = [\"/api/v3/search/\"]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[response_transform]\n" + tt.section + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.ResponseTransform.MaxBodyBytes != 1024*1024 {
+				t.Errorf("ResponseTransform.MaxBodyBytes = %d, want default %d", cfg.ResponseTransform.MaxBodyBytes, 1024*1024)
+			}
+		})
+	}
+}
+
+func TestLoad_RequiredParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"valid", `"/api/v3/search/lucene/" = ["query"]`, false},
+		{"relative path", `"api/v3/search/lucene/" = ["query"]`, true},
+		{"apiKey required", `"/api/v3/search/lucene/" = ["apiKey"]`, true},
+		{"empty name", `"/api/v3/search/lucene/" = [""]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[server.required_params]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_LogOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"default", ``, false},
+		{"file", "output = \"file\"\nfile_path = \"/var/log/vulners-proxy/proxy.log\"\nmax_backups = 5", false},
+		{"file without path", `output = "file"`, true},
+		{"unknown output", `output = "syslog"`, true},
+		{"negative rotation", "max_age_days = -1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[log]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.Log.Output == "" || cfg.Log.MaxSizeMB != 100) {
+				t.Errorf("Log = %+v, want default output and max_size_mb 100", cfg.Log)
+			}
+		})
+	}
+}
+
+func TestLoad_AuditOutputDefault(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[log]\naudit_enabled = true\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cliWithPath(path))
 	if err != nil {
-		t.Fatalf("Load() error = %v; disabled metrics should skip path validation", err)
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Log.AuditOutput != "stdout" {
+		t.Errorf("Log.AuditOutput = %q, want %q", cfg.Log.AuditOutput, "stdout")
 	}
 }
 
-func TestServerConfig_Addr(t *testing.T) {
-	sc := &ServerConfig{Host: "127.0.0.1", Port: 3000}
-	want := "127.0.0.1:3000"
-	if got := sc.Addr(); got != want {
-		t.Errorf("Addr() = %q, want %q", got, want)
+func TestLoad_Maintenance(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"defaults", ``, false},
+		{"valid", "enabled = true\nretry_after = \"10m\"\nadmin_token = \"0123456789abcdef\"", false},
+		{"negative retry_after", `retry_after = "-1m"`, true},
+		{"short admin token", `admin_token = "short"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[maintenance]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.Maintenance.Message == "" || cfg.Maintenance.RetryAfter <= 0) {
+				t.Errorf("Maintenance = %+v, want default message and retry_after", cfg.Maintenance)
+			}
+		})
+	}
+}
+
+func TestLoad_AdminToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"unset", "", "", false},
+		{"admin", "[admin]\ntoken = \"0123456789abcdef\"", "0123456789abcdef", false},
+		{"legacy maintenance", "[maintenance]\nadmin_token = \"fedcba9876543210\"", "fedcba9876543210", false},
+		{"both", "[admin]\ntoken = \"0123456789abcdef\"\n\n[maintenance]\nadmin_token = \"fedcba9876543210\"", "", true},
+		{"short", "[admin]\ntoken = \"short\"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n" + tt.data + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.AdminToken() != tt.want {
+				t.Errorf("AdminToken() = %q, want %q", cfg.AdminToken(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_TimeoutFloor(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		wantErr  bool
+		wantWarn bool
+	}{
+		{"default", "", false, false},
+		{"sane", `timeout = "30s"`, false, false},
+		{"at floor", "timeout_seconds = 5", false, false},
+		{"low warns", "timeout_seconds = 1", false, true},
+		{"sub-second warns", `timeout = "500ms"`, false, true},
+		{"strict rejects", "timeout_seconds = 1\nstrict_timeouts = true", true, false},
+		{"strict rejects duration form", "timeout = \"2s\"\nstrict_timeouts = true", true, false},
+		{"strict allows default", "strict_timeouts = true", false, false},
+		{"strict allows sane", "timeout = \"10s\"\nstrict_timeouts = true", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var buf bytes.Buffer
+			cfg.WarnTimeouts(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+			if got := strings.Contains(buf.String(), "upstream.timeout is unusually low"); got != tt.wantWarn {
+				t.Errorf("warned = %v, want %v; log: %q", got, tt.wantWarn, buf.String())
+			}
+		})
+	}
+}
+
+func TestWarnPermissions_Loose(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits not meaningful on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("# test"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{filePath: path}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	cfg.WarnPermissions(logger)
+
+	if !strings.Contains(buf.String(), "readable by group/others") {
+		t.Errorf("expected permission warning, got: %q", buf.String())
+	}
+}
+
+func TestWarnPermissions_Strict(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits not meaningful on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("# test"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{filePath: path}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	cfg.WarnPermissions(logger)
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no warning for 0600 file, got: %q", buf.String())
+	}
+}
+
+func TestFindConfigInPaths_Found(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("[upstream]\nbase_url = \"https://vulners.com\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findConfigInPaths([]string{path})
+	if got != path {
+		t.Errorf("findConfigInPaths() = %q, want %q", got, path)
+	}
+}
+
+func TestFindConfigInPaths_NotFound(t *testing.T) {
+	got := findConfigInPaths([]string{"/nonexistent/a.toml", "/nonexistent/b.toml"})
+	if got != "" {
+		t.Errorf("findConfigInPaths() = %q, want empty", got)
+	}
+}
+
+func TestFindConfigInPaths_Priority(t *testing.T) {
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	path1 := filepath.Join(dir1, "config.toml")
+	path2 := filepath.Join(dir2, "config.toml")
+	for _, p := range []string{path1, path2} {
+		if err := os.WriteFile(p, []byte("[upstream]\nbase_url = \"https://vulners.com\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := findConfigInPaths([]string{path1, path2})
+	if got != path1 {
+		t.Errorf("findConfigInPaths() = %q, want first match %q", got, path1)
+	}
+}
+
+func TestLoad_MetricsPathDefault(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[metrics]
+enabled = true
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Metrics.Path != "/metrics" {
+		t.Errorf("Metrics.Path = %q, want %q", cfg.Metrics.Path, "/metrics")
+	}
+}
+
+func TestLoad_MetricsPathNoLeadingSlash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[metrics]
+enabled = true
+path = "metrics"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(cliWithPath(path))
+	if err == nil {
+		t.Fatal("Load() expected error for metrics.path without leading slash, got nil")
+	}
+	if !strings.Contains(err.Error(), "metrics.path") {
+		t.Errorf("error = %q, want mention of metrics.path", err)
+	}
+}
+
+func TestLoad_MetricsPathConflictsWithAPIRoute(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"api/v3 exact", "/api/v3"},
+		{"api/v3 sub", "/api/v3/metrics"},
+		{"api/v4 exact", "/api/v4"},
+		{"healthz", "/healthz"},
+		{"proxy/status", "/proxy/status"},
+		{"proxy/maintenance", "/proxy/maintenance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfgPath := filepath.Join(dir, "config.toml")
+			data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[metrics]
+enabled = true
+path = "` + tt.path + `"
+`
+			if err := os.WriteFile(cfgPath, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(cfgPath))
+			if err == nil {
+				t.Fatalf("Load() expected error for metrics.path=%q conflicting with route, got nil", tt.path)
+			}
+			if !strings.Contains(err.Error(), "conflicts") {
+				t.Errorf("error = %q, want mention of conflict", err)
+			}
+		})
+	}
+}
+
+func TestLoad_MetricsPathValid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[metrics]
+enabled = true
+path = "/custom-metrics"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Metrics.Path != "/custom-metrics" {
+		t.Errorf("Metrics.Path = %q, want %q", cfg.Metrics.Path, "/custom-metrics")
+	}
+}
+
+func TestLoad_MetricsDisabledSkipsPathValidation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	data := `
+[upstream]
+base_url = "https://vulners.com"
+
+[metrics]
+enabled = false
+path = "bad-no-slash"
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(cliWithPath(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v; disabled metrics should skip path validation", err)
+	}
+}
+
+func TestServerConfig_Addr(t *testing.T) {
+	sc := &ServerConfig{Host: "127.0.0.1", Port: 3000}
+	want := "127.0.0.1:3000"
+	if got := sc.Addr(); got != want {
+		t.Errorf("Addr() = %q, want %q", got, want)
+	}
+}
+
+func TestLoad_BodyLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"valid", "methods = [\"POST\"]\npath_prefix = \"/api/v4/audit\"\nmax_bytes = 104857600", false},
+		{"no limit", "path_prefix = \"/api/v4/audit\"\nmax_bytes = 0", false},
+		{"relative prefix", "path_prefix = \"api/v4\"\nmax_bytes = 1", true},
+		{"negative max", "path_prefix = \"/api/v4\"\nmax_bytes = -1", true},
+		{"empty method", "methods = [\"\"]\npath_prefix = \"/api/v4\"\nmax_bytes = 1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[[server.body_limits]]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(cfg.Server.BodyLimits) != 1 || cfg.Server.BodyLimits[0].PathPrefix != "/api/v4/audit" {
+				t.Errorf("Server.BodyLimits = %+v", cfg.Server.BodyLimits)
+			}
+		})
+	}
+}
+
+func TestLoad_Liveness(t *testing.T) {
+	tests := []struct {
+		name          string
+		entry         string
+		wantInterval  time.Duration
+		wantThreshold time.Duration
+		wantErr       bool
+	}{
+		{"defaults", "enabled = true", time.Second, 10 * time.Second, false},
+		{"durations", "enabled = true\nheartbeat_interval = \"2s\"\nstall_threshold = \"30s\"", 2 * time.Second, 30 * time.Second, false},
+		{"integer seconds", "enabled = true\nheartbeat_interval_seconds = 5\nstall_threshold_seconds = 60", 5 * time.Second, time.Minute, false},
+		{"both forms", "heartbeat_interval = \"2s\"\nheartbeat_interval_seconds = 2", 0, 0, true},
+		{"threshold not above interval", "enabled = true\nheartbeat_interval = \"10s\"", 0, 0, true},
+		{"legacy threshold not above default interval", "enabled = true\nstall_threshold_seconds = 1", 0, 0, true},
+		{"negative", "stall_threshold_seconds = -1", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[liveness]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Liveness.HeartbeatInterval.Std(); got != tt.wantInterval {
+				t.Errorf("Liveness.HeartbeatInterval = %s, want %s", got, tt.wantInterval)
+			}
+			if got := cfg.Liveness.StallThreshold.Std(); got != tt.wantThreshold {
+				t.Errorf("Liveness.StallThreshold = %s, want %s", got, tt.wantThreshold)
+			}
+		})
+	}
+}
+
+func TestLoad_TCPKeepAlive(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", 30 * time.Second, false},
+		{"duration", `tcp_keepalive = "1m"`, time.Minute, false},
+		{"integer seconds", "tcp_keepalive_seconds = 45", 45 * time.Second, false},
+		{"both forms", "tcp_keepalive = \"1m\"\ntcp_keepalive_seconds = 60", 0, true},
+		{"negative", "tcp_keepalive_seconds = -1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Server.TCPKeepAlive.Std(); got != tt.want {
+				t.Errorf("Server.TCPKeepAlive = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_IdleConnTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", 90 * time.Second, false},
+		{"duration", `idle_conn_timeout = "25s"`, 25 * time.Second, false},
+		{"integer seconds", "idle_conn_timeout_seconds = 30", 30 * time.Second, false},
+		{"both forms", "idle_conn_timeout = \"25s\"\nidle_conn_timeout_seconds = 25", 0, true},
+		{"negative", "idle_conn_timeout_seconds = -1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Upstream.IdleConnTimeout.Std(); got != tt.want {
+				t.Errorf("Upstream.IdleConnTimeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_StatusRemap(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"empty", ``, false},
+		{"valid", `"501" = 404`, false},
+		{"to success", `"203" = 200`, false},
+		{"non-numeric key", `"5xx" = 404`, true},
+		{"key out of range", `"600" = 404`, true},
+		{"redirect target", `"501" = 302`, true},
+		{"no content target", `"501" = 204`, true},
+		{"target out of range", `"501" = 99`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[upstream.status_remap]\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_MaxInFlight(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     string
+		wantRetry time.Duration
+		wantErr   bool
+	}{
+		{"default", "", time.Second, false},
+		{"enabled", "max_in_flight = 500\nshed_retry_after = \"5s\"", 5 * time.Second, false},
+		{"negative max_in_flight", "max_in_flight = -1", 0, true},
+		{"negative shed_retry_after", `shed_retry_after = "-1s"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Server.ShedRetryAfter.Std(); got != tt.wantRetry {
+				t.Errorf("Server.ShedRetryAfter = %s, want %s", got, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestLoad_MaxPathLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    int
+		wantErr bool
+	}{
+		{"default", "", 8192, false},
+		{"custom", "max_path_length = 2048", 2048, false},
+		{"negative", "max_path_length = -1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Server.MaxPathLength != tt.want {
+				t.Errorf("Server.MaxPathLength = %d, want %d", cfg.Server.MaxPathLength, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_MaxQueryParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    int
+		wantErr bool
+	}{
+		{"default", "", 100, false},
+		{"custom", "max_query_params = 20", 20, false},
+		{"negative", "max_query_params = -1", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Server.MaxQueryParams != tt.want {
+				t.Errorf("Server.MaxQueryParams = %d, want %d", cfg.Server.MaxQueryParams, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ExposeVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  bool
+	}{
+		{"default", "", true},
+		{"enabled", "expose_version = true", true},
+		{"disabled", "expose_version = false", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := cfg.Server.VersionExposed(); got != tt.want {
+				t.Errorf("Server.VersionExposed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_SecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{"X-Frame-Options": "DENY", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:  "override",
+			block: `frame_options = "SAMEORIGIN"`,
+			want:  map[string]string{"X-Frame-Options": "SAMEORIGIN", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:  "disable",
+			block: "frame_options = \"\"\ncontent_type_options = \"\"",
+			want:  map[string]string{},
+		},
+		{
+			name:    "line break",
+			block:   `frame_options = "DENY\r\nSet-Cookie: x=y"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server.security_headers]\n" + tt.block + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Server.SecurityHeaders.Headers(); !maps.Equal(got, tt.want) {
+				t.Errorf("SecurityHeaders.Headers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_CanaryBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		canary  string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"https", "https://mirror.vulners.com", false},
+		{"http", "http://mirror.vulners.com", true},
+		{"query string", "https://mirror.vulners.com/?a=b", true},
+		{"dot segments", "https://mirror.vulners.com/a/../b", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := fmt.Sprintf("[upstream]\nbase_url = \"https://vulners.com\"\ncanary_base_url = %q\n", tt.canary)
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Upstream.CanaryBaseURL != tt.canary {
+				t.Errorf("Upstream.CanaryBaseURL = %q, want %q", cfg.Upstream.CanaryBaseURL, tt.canary)
+			}
+		})
+	}
+}
+
+func TestLoad_SuccessWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", time.Minute, false},
+		{"custom", `success_window = "5m"`, 5 * time.Minute, false},
+		{"too short", `success_window = "500ms"`, 0, true},
+		{"negative", `success_window = "-1m"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Upstream.SuccessWindow.Std() != tt.want {
+				t.Errorf("Upstream.SuccessWindow = %s, want %s", cfg.Upstream.SuccessWindow.Std(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_LogSampleRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    float64
+		wantErr bool
+	}{
+		{"default", "", 1, false},
+		{"fraction", "sample_rate = 0.1", 0.1, false},
+		{"zero", "sample_rate = 0.0", 0, false},
+		{"above one", "sample_rate = 1.5", 0, true},
+		{"negative", "sample_rate = -0.1", 0, true},
+		{"nan", "sample_rate = nan", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[log]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Log.RequestSampleRate() != tt.want {
+				t.Errorf("Log.RequestSampleRate() = %v, want %v", cfg.Log.RequestSampleRate(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Environment(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{"unset", "", false},
+		{"set", `environment = "prod"`, false},
+		{"exposed", "environment = \"staging\"\nexpose_environment = true", false},
+		{"exposed without environment", "expose_environment = true", true},
+		{"control characters", `environment = "prod\r\nX-Evil: 1"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[server]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(cliWithPath(path)); (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_MinKeyLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		vulners string
+		wantErr bool
+	}{
+		{"empty key allowed", `api_key = ""`, false},
+		{"default minimum met", `api_key = "abcdefgh"`, false},
+		{"too short", `api_key = "abcd"`, true},
+		{"secondary too short", "api_key = \"abcdefgh\"\nsecondary_api_key = \"abcd\"", true},
+		{"custom minimum", "api_key = \"abcdefgh\"\nmin_key_length = 32", true},
+		{"lowered minimum", "api_key = \"abcd\"\nmin_key_length = 4", false},
+		{"negative minimum", "min_key_length = -1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[vulners]\n" + tt.vulners + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_KeyPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{"default", "", "config", false},
+		{"config", `key_precedence = "config"`, "config", false},
+		{"header", `key_precedence = "header"`, "header", false},
+		{"invalid", `key_precedence = "tenant"`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[vulners]\n" + tt.entry + "\n\n[upstream]\nbase_url = \"https://vulners.com\"\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Vulners.KeyPrecedence != tt.want {
+				t.Errorf("KeyPrecedence = %q, want %q", cfg.Vulners.KeyPrecedence, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_TenantKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		vulners    string
+		wantErr    bool
+		wantHeader string
+	}{
+		{"none", ``, false, "X-Tenant-Id"},
+		{"valid", "[vulners.tenant_keys]\nacme = \"acme-key-12345\"", false, "X-Tenant-Id"},
+		{"custom header", "tenant_header = \"X-Vulners-Tenant\"\n[vulners.tenant_keys]\nacme = \"acme-key-12345\"", false, "X-Vulners-Tenant"},
+		{"key too short", "[vulners.tenant_keys]\nacme = \"abcd\"", true, ""},
+		{"placeholder key", "[vulners.tenant_keys]\nacme = \"YOUR_API_KEY_HERE\"", true, ""},
+		{"empty tenant ID", "[vulners.tenant_keys]\n\"\" = \"acme-key-12345\"", true, ""},
+		{"invalid header", `tenant_header = "X Tenant"`, true, ""},
+		{"same as api_key_header", `tenant_header = "x-api-key"`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[vulners]\n" + tt.vulners + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Vulners.TenantHeader != tt.wantHeader {
+				t.Errorf("TenantHeader = %q, want %q", cfg.Vulners.TenantHeader, tt.wantHeader)
+			}
+		})
+	}
+}
+
+func TestLoad_Shadow(t *testing.T) {
+	tests := []struct {
+		name    string
+		shadow  string
+		wantErr bool
+	}{
+		{"disabled", ``, false},
+		{"enabled", "enabled = true\nbase_url = \"https://mirror.vulners.com\"", false},
+		{"missing base_url", "enabled = true", true},
+		{"plain HTTP", "enabled = true\nbase_url = \"http://mirror.vulners.com\"", true},
+		{"base path", "enabled = true\nbase_url = \"https://mirror.vulners.com/v2/\"", false},
+		{"query string", "enabled = true\nbase_url = \"https://mirror.vulners.com/?a=b\"", true},
+		{"dot segments", "enabled = true\nbase_url = \"https://mirror.vulners.com/a/../b\"", true},
+		{"negative timeout", "timeout = \"-1s\"", true},
+		{"negative max_in_flight", "max_in_flight = -1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[shadow]\n" + tt.shadow + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.Shadow.Timeout.Std() != 30*time.Second || cfg.Shadow.MaxInFlight != 100 {
+				t.Errorf("Shadow defaults = %s, %d; want 30s, 100", cfg.Shadow.Timeout.Std(), cfg.Shadow.MaxInFlight)
+			}
+		})
+	}
+}
+
+func TestLoad_StatusCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		entry        string
+		wantTimeout  time.Duration
+		wantInterval time.Duration
+		wantErr      bool
+	}{
+		{"defaults", "", 5 * time.Second, 10 * time.Second, false},
+		{"custom", "timeout = \"2s\"\nmin_interval = \"1m\"", 2 * time.Second, time.Minute, false},
+		{"negative timeout", `timeout = "-1s"`, 0, 0, true},
+		{"negative interval", `min_interval = "-1s"`, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.toml")
+			data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[status_check]\nenabled = true\n" + tt.entry + "\n"
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(cliWithPath(path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !cfg.StatusCheck.Enabled {
+				t.Error("StatusCheck.Enabled = false, want true")
+			}
+			if got := cfg.StatusCheck.Timeout.Std(); got != tt.wantTimeout {
+				t.Errorf("StatusCheck.Timeout = %s, want %s", got, tt.wantTimeout)
+			}
+			if got := cfg.StatusCheck.MinInterval.Std(); got != tt.wantInterval {
+				t.Errorf("StatusCheck.MinInterval = %s, want %s", got, tt.wantInterval)
+			}
+		})
 	}
 }
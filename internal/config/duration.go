@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Duration is a time.Duration that decodes from Go duration strings such as
+// "90s", "1m30s", or "500ms" in TOML.
+type Duration time.Duration
+
+// UnmarshalText parses a Go duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		// A bare number is the most common mistake; say what to write instead.
+		if _, numErr := strconv.ParseFloat(string(text), 64); numErr == nil {
+			return fmt.Errorf("invalid duration %q: a unit is required, e.g. \"%ss\"", text, text)
+		}
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats d as a Go duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Std returns d as a time.Duration.
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// checkDuration validates a duration key together with the integer key it
+// supersedes. Setting both is rejected so the effective value is never
+// ambiguous.
+func checkDuration(name string, d Duration, legacyName string, legacy int) error {
+	if d < 0 {
+		return fmt.Errorf("%s must be non-negative; got %s", name, d.Std())
+	}
+	if legacy < 0 {
+		return fmt.Errorf("%s must be non-negative; got %d", legacyName, legacy)
+	}
+	if d != 0 && legacy != 0 {
+		return fmt.Errorf("set only one of %s and %s", name, legacyName)
+	}
+	return nil
+}
+
+// fromLegacy returns d, or the legacy integer value in unit when d is unset.
+func fromLegacy(d Duration, legacy int, unit time.Duration) Duration {
+	if d != 0 {
+		return d
+	}
+	return Duration(time.Duration(legacy) * unit)
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/audit"
+)
+
+// DebugEnabled reports whether GET /proxy/debug/headers should be exposed.
+// It requires admin.token.
+func (h *ProxyHandler) DebugEnabled() bool {
+	return h.adminToken != ""
+}
+
+// DebugHeaders handles GET /proxy/debug/headers, answering with the headers
+// the proxy would send upstream for this request, to find out why a header
+// does not reach Vulners. The upstream API key is redacted. The caller must
+// send the admin token as "Authorization: Bearer <token>"; the Authorization
+// header is never forwarded, so it does not appear in the result.
+func (h *ProxyHandler) DebugHeaders(c echo.Context) error {
+	req := c.Request()
+	if !bearerTokenValid(req.Header.Get(echo.HeaderAuthorization), h.adminToken) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"headers": h.service.UpstreamHeaders(req.Header, req.Host),
+	})
+}
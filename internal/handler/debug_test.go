@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/client"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/service"
+)
+
+func TestProxyHandler_DebugHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		configKey  string
+		auth       string
+		header     map[string]string
+		wantCode   int
+		wantHeader map[string]string // "" means absent
+	}{
+		{
+			name:      "filtered headers with redacted config key",
+			configKey: "config-key-12345",
+			auth:      "Bearer " + testAdminToken,
+			header:    map[string]string{"X-Vulners-Trace": "abc", "X-Custom": "dropped", "Accept": "text/plain"},
+			wantCode:  http.StatusOK,
+			wantHeader: map[string]string{
+				"X-Vulners-Trace": "abc",
+				"Accept":          "text/plain",
+				"X-Api-Key":       "[REDACTED]",
+				"X-Custom":        "",
+				"Authorization":   "",
+			},
+		},
+		{
+			name:       "client key redacted",
+			auth:       "Bearer " + testAdminToken,
+			header:     map[string]string{"X-Api-Key": "client-key-12345"},
+			wantCode:   http.StatusOK,
+			wantHeader: map[string]string{"X-Api-Key": "[REDACTED]"},
+		},
+		{
+			name:       "no key resolved",
+			auth:       "Bearer " + testAdminToken,
+			wantCode:   http.StatusOK,
+			wantHeader: map[string]string{"X-Api-Key": ""},
+		},
+		{"missing token", "config-key-12345", "", nil, http.StatusUnauthorized, nil},
+		{"wrong token", "config-key-12345", "Bearer wrong-token-000000", nil, http.StatusUnauthorized, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Vulners:     config.VulnersConfig{APIKey: tt.configKey},
+				Upstream:    config.UpstreamConfig{BaseURL: "https://vulners.com", IdleConnections: 1, ForwardHeaderPrefixes: []string{"x-vulners-"}},
+				Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			svc, err := service.NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, nil)
+			if err != nil {
+				t.Fatalf("NewProxyServiceForTest: %v", err)
+			}
+			h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/proxy/debug/headers", http.NoBody)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.DebugHeaders(c); err != nil {
+				t.Fatalf("DebugHeaders() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Headers http.Header `json:"headers"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			for name, want := range tt.wantHeader {
+				if got := body.Headers.Get(name); got != want {
+					t.Errorf("header %q = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
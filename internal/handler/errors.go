@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// errorJSON writes the standard error body shared by all proxy endpoints:
+// {"error": "<message>", "request_id": "<id>"}. The request ID is omitted
+// when the RequestID middleware is not installed.
+func errorJSON(c echo.Context, code int, message string) error {
+	body := map[string]string{"error": message}
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		body["request_id"] = id
+	}
+	return c.JSON(code, body)
+}
+
+// HTTPErrorHandler returns an Echo error handler that renders framework errors
+// (unknown routes, disallowed methods, body limit, rate limit, panics) in the
+// same JSON shape as proxy errors. Headers already set on the response, such
+// as Allow on 405, are preserved.
+func HTTPErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
+	logger = logger.With("component", "http_error_handler")
+
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		code := http.StatusInternalServerError
+		message := "internal server error"
+
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			if inner, ok := he.Internal.(*echo.HTTPError); ok {
+				he = inner
+			}
+			code = he.Code
+			message = http.StatusText(code)
+			if m, ok := he.Message.(string); ok && m != "" {
+				message = m
+			}
+		}
+
+		if code >= http.StatusInternalServerError {
+			logger.Error("unhandled error",
+				"err", sanitizeError(err),
+				"path", c.Request().URL.Path,
+			)
+		}
+
+		var werr error
+		if c.Request().Method == http.MethodHead {
+			werr = c.NoContent(code)
+		} else {
+			werr = errorJSON(c, code, message)
+		}
+		if werr != nil {
+			logger.Error("writing error response", "err", werr)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+func newErrorHandlerEcho() *echo.Echo {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(logger)
+	e.Use(echomw.RequestID())
+	e.GET("/known", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/boom", func(_ echo.Context) error {
+		return errors.New("database password is hunter2")
+	})
+	return e
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := newErrorHandlerEcho()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{"unknown path", http.MethodGet, "/unknown", http.StatusNotFound, "Not Found"},
+		{"wrong method", http.MethodPost, "/known", http.StatusMethodNotAllowed, "Method Not Allowed"},
+		{"plain error hides details", http.MethodGet, "/boom", http.StatusInternalServerError, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, http.NoBody)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != echo.MIMEApplicationJSON {
+				t.Errorf("Content-Type = %q, want %q", ct, echo.MIMEApplicationJSON)
+			}
+
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if body["error"] != tt.wantMessage {
+				t.Errorf("error = %q, want %q", body["error"], tt.wantMessage)
+			}
+			if body["request_id"] == "" || body["request_id"] != rec.Header().Get(echo.HeaderXRequestID) {
+				t.Errorf("request_id = %q, want response X-Request-Id %q", body["request_id"], rec.Header().Get(echo.HeaderXRequestID))
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler_PreservesAllowHeader(t *testing.T) {
+	e := newErrorHandlerEcho()
+
+	req := httptest.NewRequest(http.MethodDelete, "/known", http.NoBody)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if allow := rec.Header().Get(echo.HeaderAllow); allow == "" {
+		t.Error("expected Allow header on 405 response")
+	}
+}
+
+func TestHTTPErrorHandler_HEADHasNoBody(t *testing.T) {
+	e := newErrorHandlerEcho()
+
+	req := httptest.NewRequest(http.MethodHead, "/unknown", http.NoBody)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", rec.Body.String())
+	}
+}
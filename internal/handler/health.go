@@ -1,11 +1,19 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/middleware"
+	"vulners-proxy-go/internal/service"
 )
 
 // Version is a string type for dependency injection of the build version.
@@ -13,27 +21,146 @@ type Version string
 
 // HealthHandler serves health and status endpoints.
 type HealthHandler struct {
-	cfg     *config.Config
-	version Version
+	cfg       *config.Config
+	version   Version
+	audit     *audit.Logger               // nil when auditing is disabled
+	rateLimit *middleware.AdjustableStore // nil when rate limiting is disabled
+	upstream  *client.VulnersClient       // source of the upstream success ratio; may be nil
+	check     *upstreamCheck              // nil unless status_check.enabled
+
+	lastBeat atomic.Int64 // UnixNano of the last heartbeat tick
+	now      func() time.Time
 }
 
-// NewHealthHandler creates a HealthHandler.
-func NewHealthHandler(cfg *config.Config, v Version) *HealthHandler {
-	return &HealthHandler{cfg: cfg, version: v}
+// NewHealthHandler creates a HealthHandler. svc runs the live upstream check
+// of /proxy/status?check=true; it may be nil when the check is disabled.
+func NewHealthHandler(cfg *config.Config, v Version, auditLog *audit.Logger, rateLimit *middleware.AdjustableStore, upstream *client.VulnersClient, svc *service.ProxyService) *HealthHandler {
+	h := &HealthHandler{cfg: cfg, version: v, audit: auditLog, rateLimit: rateLimit, upstream: upstream, now: time.Now}
+	if cfg.StatusCheck.Enabled && svc != nil {
+		h.check = newUpstreamCheck(cfg, svc)
+	}
+	h.lastBeat.Store(h.now().UnixNano())
+	return h
 }
 
-// Healthz returns a simple OK response for liveness probes.
+// Healthz returns a simple OK response for liveness probes. With
+// liveness.enabled it answers 503 once the heartbeat has stalled.
 func (h *HealthHandler) Healthz(c echo.Context) error {
+	if h.cfg.Liveness.Enabled {
+		since := h.now().Sub(time.Unix(0, h.lastBeat.Load()))
+		if since > h.cfg.Liveness.StallThreshold.Std() {
+			return c.JSON(http.StatusServiceUnavailable, map[string]any{
+				"status":             "stalled",
+				"last_heartbeat_ms":  since.Milliseconds(),
+				"stall_threshold_ms": h.cfg.Liveness.StallThreshold.Std().Milliseconds(),
+			})
+		}
+	}
 	return c.JSON(http.StatusOK, map[string]string{
 		"status": "ok",
 	})
 }
 
-// Status returns proxy status information.
+// RunHeartbeat records a heartbeat every liveness.heartbeat_interval until
+// ctx is done. It runs on its own goroutine, so a stall means the runtime
+// can no longer schedule it.
+func (h *HealthHandler) RunHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.Liveness.HeartbeatInterval.Std())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.lastBeat.Store(h.now().UnixNano())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Status returns proxy status information. The config file path and
+// modification time are included only for callers that send
+// admin.token as a bearer token, when one is set.
+//
+// With ?check=true and status_check.enabled it also checks upstream live and
+// reports the result as "upstream_check". The check sends a real upstream
+// request, so it always requires the admin token when one is set.
 func (h *HealthHandler) Status(c echo.Context) error {
-	return c.JSON(http.StatusOK, map[string]string{
+	token := h.cfg.AdminToken()
+	authorized := token == "" || bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), token)
+
+	var check *upstreamCheckResult
+	if c.QueryParam("check") == "true" {
+		if h.check == nil {
+			return errorJSON(c, http.StatusBadRequest, "live upstream check is disabled; set status_check.enabled")
+		}
+		if !authorized {
+			h.audit.Record(c, audit.EventAdminDenied)
+			return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+		}
+		res := h.check.run(c.Request().Context())
+		check = &res
+	}
+
+	body := map[string]any{
 		"status":       "ok",
 		"version":      string(h.version),
 		"upstream_url": h.cfg.Upstream.BaseURL,
+		"features":     h.features(),
+	}
+	// The environment is public so that clients can confirm which
+	// deployment they are talking to.
+	if env := h.cfg.Server.Environment; env != "" {
+		body["environment"] = env
+	}
+	if h.rateLimit != nil {
+		body["rate_limit_rps"] = h.rateLimit.Rate()
+	}
+	if h.upstream != nil {
+		ratio, calls := h.upstream.SuccessRatio()
+		body["upstream_success_ratio"] = ratio
+		body["upstream_calls_in_window"] = calls
+	}
+	if check != nil {
+		body["upstream_check"] = check
+	}
+	if path := h.cfg.FilePath(); path != "" && authorized {
+		body["config_path"] = path
+		// Stat on every call so an edited file shows up even though the
+		// running process has not re-read it.
+		if info, err := os.Stat(path); err == nil {
+			body["config_modified_at"] = info.ModTime().UTC().Format(time.RFC3339)
+		}
+	}
+	return c.JSON(http.StatusOK, body)
+}
+
+// AllowedHosts handles GET /proxy/allowed-hosts, listing the upstream hosts
+// the proxy will forward to. The list reveals network topology, so when
+// admin.token is set the caller must send it as
+// "Authorization: Bearer <token>".
+func (h *HealthHandler) AllowedHosts(c echo.Context) error {
+	if token := h.cfg.AdminToken(); token != "" &&
+		!bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), token) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+	return c.JSON(http.StatusOK, map[string]any{
+		"allowed_hosts": service.AllowedUpstreamHosts(),
 	})
 }
+
+// features summarizes which optional subsystems are enabled in config.
+// Debug features are listed too, so one left on in production is visible.
+func (h *HealthHandler) features() map[string]bool {
+	return map[string]bool{
+		"rate_limit":              h.cfg.Server.RateLimit.Enabled,
+		"metrics":                 h.cfg.Metrics.Enabled,
+		"upstream_queue":          h.cfg.Upstream.MaxConcurrentRequests > 0,
+		"shared_api_key":          h.cfg.Vulners.APIKey != "",
+		"audit_log":               h.cfg.Log.AuditEnabled,
+		"liveness":                h.cfg.Liveness.Enabled,
+		"load_shedding":           h.cfg.Server.MaxInFlight > 0,
+		"status_check":            h.cfg.StatusCheck.Enabled,
+		"debug_latency_injection": h.cfg.Debug.LatencyInjection() > 0,
+	}
+}
@@ -1,13 +1,23 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
 )
 
@@ -17,7 +27,7 @@ func TestHealthz(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	h := NewHealthHandler(&config.Config{}, "test")
+	h := NewHealthHandler(&config.Config{}, "test", nil, nil, nil, nil)
 	if err := h.Healthz(c); err != nil {
 		t.Fatalf("Healthz() error = %v", err)
 	}
@@ -44,7 +54,7 @@ func TestStatus(t *testing.T) {
 	cfg := &config.Config{
 		Upstream: config.UpstreamConfig{BaseURL: "https://vulners.com"},
 	}
-	h := NewHealthHandler(cfg, "1.2.3")
+	h := NewHealthHandler(cfg, "1.2.3", nil, nil, nil, nil)
 	if err := h.Status(c); err != nil {
 		t.Fatalf("Status() error = %v", err)
 	}
@@ -53,17 +63,474 @@ func TestStatus(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
 	}
 
-	var body map[string]string
+	var body struct {
+		Status      string          `json:"status"`
+		Version     string          `json:"version"`
+		UpstreamURL string          `json:"upstream_url"`
+		Features    map[string]bool `json:"features"`
+	}
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	if body["status"] != "ok" {
-		t.Errorf("body.status = %q, want %q", body["status"], "ok")
+	if body.Status != "ok" {
+		t.Errorf("body.status = %q, want %q", body.Status, "ok")
+	}
+	if body.Version != "1.2.3" {
+		t.Errorf("body.version = %q, want %q", body.Version, "1.2.3")
+	}
+	if body.UpstreamURL != "https://vulners.com" {
+		t.Errorf("body.upstream_url = %q, want %q", body.UpstreamURL, "https://vulners.com")
+	}
+	if body.Features == nil {
+		t.Fatal("body.features missing")
+	}
+	if body.Features["rate_limit"] || body.Features["metrics"] {
+		t.Errorf("body.features = %v, want all disabled", body.Features)
+	}
+}
+
+func TestStatus_AdminToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := "[upstream]\nbase_url = \"https://vulners.com\"\n\n[maintenance]\nadmin_token = \"" + testAdminToken + "\"\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(&config.CLI{Config: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	h := NewHealthHandler(cfg, "1.2.3", nil, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantConfig bool
+	}{
+		{"valid token", "Bearer " + testAdminToken, true},
+		{"missing token", "", false},
+		{"wrong token", "Bearer wrong-token-000000", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody)
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			if err := h.Status(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			for _, key := range []string{"status", "version", "upstream_url", "features"} {
+				if _, ok := body[key]; !ok {
+					t.Errorf("%s missing", key)
+				}
+			}
+			for _, key := range []string{"config_path", "config_modified_at"} {
+				if _, ok := body[key]; ok != tt.wantConfig {
+					t.Errorf("%s present = %v, want %v", key, ok, tt.wantConfig)
+				}
+			}
+		})
+	}
+}
+
+func TestStatus_Environment(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		adminToken string
+	}{
+		{"set", "prod", ""},
+		{"unset", "", ""},
+		// The environment is public: callers without the admin token see it.
+		{"set with admin token", "prod", testAdminToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			cfg := &config.Config{
+				Server:      config.ServerConfig{Environment: tt.env},
+				Maintenance: config.MaintenanceConfig{AdminToken: tt.adminToken},
+			}
+			if err := NewHealthHandler(cfg, "test", nil, nil, nil, nil).Status(c); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			got, ok := body["environment"]
+			if tt.env == "" && ok {
+				t.Errorf("environment = %v, want it omitted when unset", got)
+			}
+			if tt.env != "" && got != tt.env {
+				t.Errorf("environment = %v, want %q", got, tt.env)
+			}
+		})
+	}
+}
+
+func TestStatus_UpstreamSuccessRatio(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{Upstream: config.UpstreamConfig{
+		BaseURL:         upstream.URL,
+		IdleConnections: 10,
+		SuccessWindow:   config.Duration(time.Minute),
+	}}
+	vc := client.NewVulnersClient(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	for _, path := range []string{"/ok", "/ok", "/ok", "/fail"} {
+		resp, err := vc.DoStream(context.Background(), http.MethodGet, upstream.URL+path, http.Header{}, http.NoBody)
+		if err != nil {
+			t.Fatalf("DoStream(%s): %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := NewHealthHandler(cfg, "test", nil, nil, vc, nil).Status(c); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	var body struct {
+		Ratio float64 `json:"upstream_success_ratio"`
+		Calls int     `json:"upstream_calls_in_window"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body.Ratio != 0.75 || body.Calls != 4 {
+		t.Errorf("upstream_success_ratio = %v over %d calls, want 0.75 over 4", body.Ratio, body.Calls)
+	}
+}
+
+func TestStatus_Features(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			RateLimit: config.RateLimitConfig{Enabled: true, RequestsPerSecond: 10},
+		},
+		Vulners:  config.VulnersConfig{APIKey: "secret"},
+		Upstream: config.UpstreamConfig{MaxConcurrentRequests: 4},
+		Metrics:  config.MetricsConfig{Enabled: true},
+		Log:      config.LogConfig{AuditEnabled: true},
+		Debug:    config.DebugConfig{Enabled: true, InjectLatency: config.Duration(time.Second)},
+	}
+	h := NewHealthHandler(cfg, "test", nil, nil, nil, nil)
+	if err := h.Status(c); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	var body struct {
+		Features map[string]bool `json:"features"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for _, name := range []string{"rate_limit", "metrics", "upstream_queue", "shared_api_key", "audit_log", "debug_latency_injection"} {
+		if !body.Features[name] {
+			t.Errorf("features[%q] = false, want true", name)
+		}
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Error("status response must not leak the API key")
+	}
+}
+
+func TestStatus_ConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[upstream]\nbase_url = \"https://vulners.com\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(&config.CLI{Config: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := NewHealthHandler(cfg, "test", nil, nil, nil, nil).Status(c); err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	var body struct {
+		ConfigPath       string `json:"config_path"`
+		ConfigModifiedAt string `json:"config_modified_at"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
 	}
-	if body["version"] != "1.2.3" {
-		t.Errorf("body.version = %q, want %q", body["version"], "1.2.3")
+	if body.ConfigPath != path {
+		t.Errorf("config_path = %q, want %q", body.ConfigPath, path)
+	}
+	if body.ConfigModifiedAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("config_modified_at = %q, want %q", body.ConfigModifiedAt, "2026-01-02T03:04:05Z")
+	}
+}
+
+func TestAllowedHosts(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		wantCode      int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"valid token", token, "Bearer " + token, http.StatusOK},
+		{"missing token", token, "", http.StatusUnauthorized},
+		{"wrong token", token, "Bearer wrong", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/proxy/allowed-hosts", http.NoBody)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			cfg := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: tt.adminToken}}
+			var auditBuf strings.Builder
+			h := NewHealthHandler(cfg, "test", audit.New(&auditBuf, "json"), nil, nil, nil)
+			if err := h.AllowedHosts(c); err != nil {
+				t.Fatalf("AllowedHosts() error = %v", err)
+			}
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if denied := strings.Contains(auditBuf.String(), audit.EventAdminDenied); denied != (tt.wantCode == http.StatusUnauthorized) {
+				t.Errorf("admin_denied audited = %v, want %v", denied, tt.wantCode == http.StatusUnauthorized)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var body struct {
+				AllowedHosts []string `json:"allowed_hosts"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if len(body.AllowedHosts) != 1 || body.AllowedHosts[0] != "vulners.com" {
+				t.Errorf("allowed_hosts = %v, want [vulners.com]", body.AllowedHosts)
+			}
+		})
+	}
+}
+
+func TestHealthz_Liveness(t *testing.T) {
+	cfg := &config.Config{
+		Liveness: config.LivenessConfig{
+			Enabled:           true,
+			HeartbeatInterval: config.Duration(10 * time.Millisecond),
+			StallThreshold:    config.Duration(time.Second),
+		},
+	}
+	h := NewHealthHandler(cfg, "test", nil, nil, nil, nil)
+	clock := time.Unix(1_700_000_000, 0)
+	h.now = func() time.Time { return clock }
+	h.lastBeat.Store(clock.UnixNano())
+
+	healthz := func() int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody), rec)
+		if err := h.Healthz(c); err != nil {
+			t.Fatalf("Healthz() error = %v", err)
+		}
+		return rec.Code
+	}
+
+	clock = clock.Add(time.Second)
+	if got := healthz(); got != http.StatusOK {
+		t.Errorf("status at threshold = %d, want %d", got, http.StatusOK)
+	}
+	clock = clock.Add(time.Millisecond)
+	if got := healthz(); got != http.StatusServiceUnavailable {
+		t.Errorf("status past threshold = %d, want %d", got, http.StatusServiceUnavailable)
+	}
+
+	// A running heartbeat brings the probe back.
+	h.now = time.Now
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.RunHeartbeat(ctx)
+	deadline := time.Now().Add(2 * time.Second)
+	for healthz() != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("heartbeat did not recover /healthz")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHealthz_LivenessDisabled(t *testing.T) {
+	h := NewHealthHandler(&config.Config{}, "test", nil, nil, nil, nil)
+	h.lastBeat.Store(0)
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody), rec)
+	if err := h.Healthz(c); err != nil {
+		t.Fatalf("Healthz() error = %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestStatus_UpstreamCheck(t *testing.T) {
+	var hits, upstreamStatus atomic.Int32
+	upstreamStatus.Store(http.StatusOK)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path != "/api/v3/apiKey/valid/" {
+			t.Errorf("check path = %q, want /api/v3/apiKey/valid/", r.URL.Path)
+		}
+		w.WriteHeader(int(upstreamStatus.Load()))
+	}))
+	defer upstream.Close()
+
+	const token = "0123456789abcdef"
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "config-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			IdleConnections: 10,
+			SuccessWindow:   config.Duration(time.Minute),
+		},
+		Startup:     config.StartupConfig{SelfTestPath: "/api/v3/apiKey/valid/"},
+		StatusCheck: config.StatusCheckConfig{Enabled: true, Timeout: config.Duration(time.Second), MinInterval: config.Duration(10 * time.Second)},
+		Maintenance: config.MaintenanceConfig{AdminToken: token},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	var auditBuf strings.Builder
+	h := NewHealthHandler(cfg, "test", audit.New(&auditBuf, "json"), nil, vc, svc)
+	clock := time.Unix(1_700_000_000, 0)
+	h.check.now = func() time.Time { return clock }
+
+	type checkBody struct {
+		Calls int                  `json:"upstream_calls_in_window"`
+		Check *upstreamCheckResult `json:"upstream_check"`
+	}
+	status := func(target, auth string) (int, checkBody) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		if auth != "" {
+			req.Header.Set(echo.HeaderAuthorization, auth)
+		}
+		rec := httptest.NewRecorder()
+		if err := h.Status(echo.New().NewContext(req, rec)); err != nil {
+			t.Fatalf("Status() error = %v", err)
+		}
+		var body checkBody
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+		}
+		return rec.Code, body
+	}
+
+	// The plain status stays public and does not touch upstream.
+	if code, body := status("/proxy/status", ""); code != http.StatusOK || body.Check != nil {
+		t.Errorf("plain status = %d with upstream_check %+v, want 200 without it", code, body.Check)
+	}
+	if code, _ := status("/proxy/status?check=true", "Bearer wrong-token-wrong-token"); code != http.StatusUnauthorized {
+		t.Errorf("check with wrong token = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if !strings.Contains(auditBuf.String(), audit.EventAdminDenied) {
+		t.Errorf("denied check not audited: %q", auditBuf.String())
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("upstream hits before an authorized check = %d, want 0", n)
+	}
+
+	code, body := status("/proxy/status?check=true", "Bearer "+token)
+	if code != http.StatusOK || body.Check == nil || !body.Check.OK || body.Check.Cached {
+		t.Fatalf("check = %d with %+v, want 200 with a fresh ok result", code, body.Check)
+	}
+
+	// Within min_interval the previous result is returned without a new
+	// upstream request, even though upstream is now failing.
+	upstreamStatus.Store(http.StatusInternalServerError)
+	clock = clock.Add(5 * time.Second)
+	_, body = status("/proxy/status?check=true", "Bearer "+token)
+	if body.Check == nil || !body.Check.OK || !body.Check.Cached {
+		t.Errorf("check within interval = %+v, want the cached ok result", body.Check)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream hits = %d, want 1", n)
+	}
+
+	clock = clock.Add(5 * time.Second)
+	_, body = status("/proxy/status?check=true", "Bearer "+token)
+	if body.Check == nil || body.Check.OK || body.Check.Cached || body.Check.Error == "" {
+		t.Errorf("check after interval = %+v, want a fresh failure with an error", body.Check)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream hits = %d, want 2", n)
+	}
+
+	// Checks do not feed the success ratio derived from real traffic.
+	if body.Calls != 0 {
+		t.Errorf("upstream_calls_in_window = %d, want 0", body.Calls)
+	}
+}
+
+func TestStatus_UpstreamCheckDisabled(t *testing.T) {
+	h := NewHealthHandler(&config.Config{}, "test", nil, nil, nil, nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/proxy/status?check=true", http.NoBody), rec)
+	if err := h.Status(c); err != nil {
+		t.Fatalf("Status() error = %v", err)
 	}
-	if body["upstream_url"] != "https://vulners.com" {
-		t.Errorf("body.upstream_url = %q, want %q", body["upstream_url"], "https://vulners.com")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
 	}
 }
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/middleware"
+)
+
+// InFlightHandler lists the requests currently being processed, to diagnose
+// requests piling up without attaching a debugger. It is exposed only when
+// admin.token is set.
+type InFlightHandler struct {
+	adminToken string
+
+	audit    *audit.Logger                // nil when auditing is disabled
+	registry *middleware.InFlightRegistry // nil when the endpoint is disabled
+}
+
+// NewInFlightHandler creates an InFlightHandler.
+func NewInFlightHandler(cfg *config.Config, auditLog *audit.Logger, registry *middleware.InFlightRegistry) *InFlightHandler {
+	return &InFlightHandler{
+		adminToken: cfg.AdminToken(),
+		audit:      auditLog,
+		registry:   registry,
+	}
+}
+
+// Enabled reports whether the in-flight endpoint should be exposed.
+func (h *InFlightHandler) Enabled() bool {
+	return h.registry != nil && h.adminToken != ""
+}
+
+// Snapshot handles GET /proxy/inflight, listing in-flight requests oldest
+// first with their method, path, client IP, request ID and age. The caller
+// must send the admin token as "Authorization: Bearer <token>".
+func (h *InFlightHandler) Snapshot(c echo.Context) error {
+	if !bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), h.adminToken) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+	requests, untracked := h.registry.Snapshot()
+	return c.JSON(http.StatusOK, map[string]any{
+		"count":     len(requests) + untracked,
+		"untracked": untracked,
+		"requests":  requests,
+	})
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/middleware"
+)
+
+func TestInFlightHandler_Snapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     string
+		wantCode int
+	}{
+		{"valid token", "Bearer " + testAdminToken, http.StatusOK},
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong-token-000000", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+			registry := middleware.NewInFlightRegistry()
+			h := NewInFlightHandler(cfg, nil, registry)
+			if !h.Enabled() {
+				t.Fatal("Enabled() = false, want true")
+			}
+
+			// Serve the snapshot through the registry's own middleware, so
+			// the snapshot request lists itself.
+			e := echo.New()
+			e.Use(registry.Middleware())
+			e.GET("/proxy/inflight", h.Snapshot)
+
+			req := httptest.NewRequest(http.MethodGet, "/proxy/inflight", http.NoBody)
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Count     int                          `json:"count"`
+				Untracked int                          `json:"untracked"`
+				Requests  []middleware.InFlightRequest `json:"requests"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if body.Count != 1 || len(body.Requests) != 1 || body.Requests[0].Path != "/proxy/inflight" {
+				t.Errorf("body = %+v, want the snapshot request itself", body)
+			}
+		})
+	}
+}
+
+func TestInFlightHandler_Enabled(t *testing.T) {
+	withToken := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+	if NewInFlightHandler(withToken, nil, nil).Enabled() {
+		t.Error("Enabled() = true without a registry, want false")
+	}
+	if NewInFlightHandler(&config.Config{}, nil, middleware.NewInFlightRegistry()).Enabled() {
+		t.Error("Enabled() = true without an admin token, want false")
+	}
+}
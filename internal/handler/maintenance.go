@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+// MaintenanceHandler holds the runtime maintenance-mode switch. While it is
+// on, Guard answers API requests with 503 and Retry-After instead of
+// forwarding them; health endpoints are unaffected.
+type MaintenanceHandler struct {
+	enabled atomic.Bool
+
+	message    string
+	retryAfter string // seconds, preformatted for the Retry-After header
+	adminToken string
+
+	logger  *slog.Logger
+	audit   *audit.Logger    // nil when auditing is disabled
+	metrics *metrics.Metrics // nil when metrics are disabled
+}
+
+// NewMaintenanceHandler creates a MaintenanceHandler in the state given by
+// cfg.Maintenance.Enabled.
+func NewMaintenanceHandler(cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, m *metrics.Metrics) *MaintenanceHandler {
+	h := &MaintenanceHandler{
+		message:    cfg.Maintenance.Message,
+		retryAfter: strconv.Itoa(int(cfg.Maintenance.RetryAfter.Std().Seconds())),
+		adminToken: cfg.AdminToken(),
+		logger:     logger.With("component", "maintenance"),
+		audit:      auditLog,
+		metrics:    m,
+	}
+	h.SetEnabled(cfg.Maintenance.Enabled)
+	return h
+}
+
+// Enabled reports whether maintenance mode is on.
+func (h *MaintenanceHandler) Enabled() bool {
+	return h.enabled.Load()
+}
+
+// SetEnabled switches maintenance mode and reports whether the state changed.
+func (h *MaintenanceHandler) SetEnabled(on bool) bool {
+	changed := h.enabled.Swap(on) != on
+	if h.metrics != nil {
+		v := 0.0
+		if on {
+			v = 1
+		}
+		h.metrics.MaintenanceMode.Set(v)
+	}
+	if changed {
+		h.logger.Warn("maintenance mode changed", "enabled", on)
+	}
+	return changed
+}
+
+// Guard is route middleware that rejects requests while maintenance mode is on.
+func (h *MaintenanceHandler) Guard(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !h.Enabled() {
+			return next(c)
+		}
+		c.Response().Header().Set("Retry-After", h.retryAfter)
+		if c.Request().Method == http.MethodHead {
+			return c.NoContent(http.StatusServiceUnavailable)
+		}
+		return errorJSON(c, http.StatusServiceUnavailable, h.message)
+	}
+}
+
+// AdminEnabled reports whether the runtime toggle endpoint should be exposed.
+func (h *MaintenanceHandler) AdminEnabled() bool {
+	return h.adminToken != ""
+}
+
+// Toggle handles POST /proxy/maintenance with a JSON body {"enabled": bool}.
+// The caller must send the admin token as "Authorization: Bearer <token>".
+func (h *MaintenanceHandler) Toggle(c echo.Context) error {
+	if !bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), h.adminToken) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := c.Bind(&body); err != nil || body.Enabled == nil {
+		return errorJSON(c, http.StatusBadRequest, `request body must be JSON of the form {"enabled": true|false}`)
+	}
+
+	if h.SetEnabled(*body.Enabled) {
+		h.audit.Record(c, audit.EventMaintenance, "enabled", *body.Enabled)
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"enabled": h.Enabled()})
+}
+
+// bearerTokenValid compares the bearer token in an Authorization header with
+// the admin token in constant time. An empty admin token matches nothing.
+func bearerTokenValid(authorization, adminToken string) bool {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
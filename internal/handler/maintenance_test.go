@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+const testAdminToken = "0123456789abcdef"
+
+func newTestMaintenanceHandler(enabled bool, m *metrics.Metrics) *MaintenanceHandler {
+	cfg := &config.Config{
+		Maintenance: config.MaintenanceConfig{
+			Enabled:    enabled,
+			Message:    "down for maintenance",
+			RetryAfter: config.Duration(5 * time.Minute),
+			AdminToken: testAdminToken,
+		},
+	}
+	return NewMaintenanceHandler(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, m)
+}
+
+func TestMaintenanceHandler_Guard(t *testing.T) {
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "forwarded") }
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		method   string
+		wantCode int
+		wantBody string
+	}{
+		{"disabled forwards", false, http.MethodGet, http.StatusOK, "forwarded"},
+		{"enabled rejects", true, http.MethodGet, http.StatusServiceUnavailable, "down for maintenance"},
+		{"enabled HEAD has no body", true, http.MethodHead, http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestMaintenanceHandler(tt.enabled, nil)
+			e := echo.New()
+			req := httptest.NewRequest(tt.method, "/api/v3/search/lucene/", http.NoBody)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := h.Guard(ok)(c); err != nil {
+				t.Fatalf("Guard() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody == "" && rec.Body.Len() != 0 {
+				t.Errorf("body = %q, want empty", rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.enabled && rec.Header().Get("Retry-After") != "300" {
+				t.Errorf("Retry-After = %q, want %q", rec.Header().Get("Retry-After"), "300")
+			}
+		})
+	}
+}
+
+func TestMaintenanceHandler_Toggle(t *testing.T) {
+	tests := []struct {
+		name        string
+		auth        string
+		body        string
+		wantCode    int
+		wantEnabled bool
+	}{
+		{"enables", "Bearer " + testAdminToken, `{"enabled":true}`, http.StatusOK, true},
+		{"missing token", "", `{"enabled":true}`, http.StatusUnauthorized, false},
+		{"wrong token", "Bearer wrong-token-000000", `{"enabled":true}`, http.StatusUnauthorized, false},
+		{"missing field", "Bearer " + testAdminToken, `{}`, http.StatusBadRequest, false},
+		{"invalid JSON", "Bearer " + testAdminToken, `enabled`, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.New()
+			h := newTestMaintenanceHandler(false, m)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/proxy/maintenance", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := h.Toggle(c); err != nil {
+				t.Fatalf("Toggle() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if h.Enabled() != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", h.Enabled(), tt.wantEnabled)
+			}
+
+			families, err := m.Registry.Gather()
+			if err != nil {
+				t.Fatalf("Gather() error = %v", err)
+			}
+			want := 0.0
+			if tt.wantEnabled {
+				want = 1
+			}
+			for _, f := range families {
+				if f.GetName() == "vulners_proxy_maintenance_mode" {
+					if got := f.GetMetric()[0].GetGauge().GetValue(); got != want {
+						t.Errorf("maintenance_mode gauge = %v, want %v", got, want)
+					}
+				}
+			}
+
+			if tt.wantCode == http.StatusOK {
+				var body map[string]bool
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				if !body["enabled"] {
+					t.Errorf("body = %v, want enabled=true", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterRoutes_MaintenanceEndpoint(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, token := range []string{"", testAdminToken} {
+		cfg := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: token}}
+		e := echo.New()
+		RegisterRoutes(e, &ProxyHandler{}, NewHealthHandler(cfg, "test", nil, nil, nil, nil), NewMaintenanceHandler(cfg, logger, nil, nil), NewMetricsJSONHandler(cfg, logger, nil, nil), NewRateLimitHandler(cfg, logger, nil, nil), NewInFlightHandler(cfg, nil, nil))
+
+		registered := false
+		for _, r := range e.Routes() {
+			if r.Method == http.MethodPost && r.Path == "/proxy/maintenance" {
+				registered = true
+			}
+		}
+		if registered != (token != "") {
+			t.Errorf("admin_token %q: /proxy/maintenance registered = %v, want %v", token, registered, token != "")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	dto "github.com/prometheus/client_model/go"
+
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+// metricsJSONPrefix limits the JSON snapshot to the proxy's own metrics; Go
+// runtime and process collectors are left to the Prometheus endpoint.
+const metricsJSONPrefix = "vulners_proxy_"
+
+// MetricsJSONHandler serves a JSON snapshot of the proxy's metrics for
+// environments without Prometheus. It is exposed only when metrics are
+// enabled and admin.token is set.
+type MetricsJSONHandler struct {
+	adminToken string
+
+	logger  *slog.Logger
+	audit   *audit.Logger    // nil when auditing is disabled
+	metrics *metrics.Metrics // nil when metrics are disabled
+}
+
+// NewMetricsJSONHandler creates a MetricsJSONHandler.
+func NewMetricsJSONHandler(cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, m *metrics.Metrics) *MetricsJSONHandler {
+	return &MetricsJSONHandler{
+		adminToken: cfg.AdminToken(),
+		logger:     logger.With("component", "metrics_json"),
+		audit:      auditLog,
+		metrics:    m,
+	}
+}
+
+// Enabled reports whether the snapshot endpoint should be exposed.
+func (h *MetricsJSONHandler) Enabled() bool {
+	return h.metrics != nil && h.adminToken != ""
+}
+
+// metricFamilyJSON is one metric family in the snapshot.
+type metricFamilyJSON struct {
+	Type    string       `json:"type"`
+	Help    string       `json:"help"`
+	Samples []sampleJSON `json:"samples"`
+}
+
+// sampleJSON is one labeled series. Counters and gauges set Value;
+// histograms set Count and Sum.
+type sampleJSON struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  *float64          `json:"value,omitempty"`
+	Count  *uint64           `json:"count,omitempty"`
+	Sum    *float64          `json:"sum,omitempty"`
+}
+
+// Snapshot handles GET /proxy/metrics.json. The caller must send the admin
+// token as "Authorization: Bearer <token>".
+func (h *MetricsJSONHandler) Snapshot(c echo.Context) error {
+	if !bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), h.adminToken) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+
+	families, err := h.metrics.Registry.Gather()
+	if err != nil {
+		// Gather returns what it could collect alongside the error.
+		h.logger.Warn("metrics gather incomplete", "err", err)
+	}
+
+	body := make(map[string]metricFamilyJSON)
+	for _, mf := range families {
+		if !strings.HasPrefix(mf.GetName(), metricsJSONPrefix) {
+			continue
+		}
+		fam := metricFamilyJSON{
+			Type:    strings.ToLower(mf.GetType().String()),
+			Help:    mf.GetHelp(),
+			Samples: make([]sampleJSON, 0, len(mf.GetMetric())),
+		}
+		for _, m := range mf.GetMetric() {
+			s := sampleJSON{}
+			if len(m.GetLabel()) > 0 {
+				s.Labels = make(map[string]string, len(m.GetLabel()))
+				for _, lp := range m.GetLabel() {
+					s.Labels[lp.GetName()] = lp.GetValue()
+				}
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v := m.GetCounter().GetValue()
+				s.Value = &v
+			case dto.MetricType_GAUGE:
+				v := m.GetGauge().GetValue()
+				s.Value = &v
+			case dto.MetricType_HISTOGRAM:
+				count, sum := m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+				s.Count, s.Sum = &count, &sum
+			default:
+				continue
+			}
+			fam.Samples = append(fam.Samples, s)
+		}
+		body[mf.GetName()] = fam
+	}
+	return c.JSON(http.StatusOK, body)
+}
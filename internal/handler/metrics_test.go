@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+func TestMetricsJSONHandler_Enabled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	withToken := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+
+	tests := []struct {
+		name string
+		cfg  *config.Config
+		m    *metrics.Metrics
+		want bool
+	}{
+		{"metrics and token", withToken, metrics.New(), true},
+		{"metrics disabled", withToken, nil, false},
+		{"no admin token", &config.Config{}, metrics.New(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMetricsJSONHandler(tt.cfg, logger, nil, tt.m).Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetricsJSONHandler_Snapshot(t *testing.T) {
+	m := metrics.New()
+	m.RequestsTotal.WithLabelValues("GET", "200", "/api/v3").Add(3)
+	m.RequestDuration.WithLabelValues("GET", "200", "/api/v3").Observe(0.5)
+	cfg := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+	h := NewMetricsJSONHandler(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, m)
+
+	tests := []struct {
+		name     string
+		auth     string
+		wantCode int
+	}{
+		{"valid token", "Bearer " + testAdminToken, http.StatusOK},
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong-token-000000", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/proxy/metrics.json", http.NoBody)
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.Snapshot(c); err != nil {
+				t.Fatalf("Snapshot() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var body map[string]metricFamilyJSON
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			for name := range body {
+				if !strings.HasPrefix(name, metricsJSONPrefix) {
+					t.Errorf("snapshot includes non-proxy metric %q", name)
+				}
+			}
+
+			requests := body["vulners_proxy_http_requests_total"]
+			if requests.Type != "counter" || len(requests.Samples) != 1 {
+				t.Fatalf("requests_total = %+v, want one counter sample", requests)
+			}
+			s := requests.Samples[0]
+			if s.Value == nil || *s.Value != 3 || s.Labels["path_prefix"] != "/api/v3" {
+				t.Errorf("requests_total sample = %+v, want value 3 for /api/v3", s)
+			}
+
+			duration := body["vulners_proxy_http_request_duration_seconds"]
+			if duration.Type != "histogram" || len(duration.Samples) != 1 {
+				t.Fatalf("request_duration = %+v, want one histogram sample", duration)
+			}
+			if d := duration.Samples[0]; d.Count == nil || *d.Count != 1 || d.Sum == nil || *d.Sum != 0.5 {
+				t.Errorf("request_duration sample = %+v, want count 1 sum 0.5", d)
+			}
+		})
+	}
+}
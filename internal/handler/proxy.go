@@ -1,21 +1,39 @@
 package handler
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
+	"mime"
 	"net"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/client"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+	"vulners-proxy-go/internal/middleware"
 	"vulners-proxy-go/internal/model"
 	"vulners-proxy-go/internal/service"
 )
 
+// statusClientClosedRequest is recorded for requests whose client went away
+// before a response could be sent. Nothing reaches the client; the code only
+// shows up in access logs and metrics. 499 follows nginx.
+const statusClientClosedRequest = 499
+
 // apiKeyPattern matches apiKey query parameter values in URLs embedded in error messages.
 var apiKeyPattern = regexp.MustCompile(`(?i)(apiKey=)[^&\s"]+`)
 
@@ -23,13 +41,65 @@ var apiKeyPattern = regexp.MustCompile(`(?i)(apiKey=)[^&\s"]+`)
 type ProxyHandler struct {
 	service *service.ProxyService
 	logger  *slog.Logger
+	audit   *audit.Logger    // nil when auditing is disabled
+	metrics *metrics.Metrics // nil when metrics are disabled
+
+	stripHeaders map[string]bool   // canonical names removed from responses
+	setHeaders   map[string]string // canonical name → forced value
+
+	bufferMaxBytes int64  // > 0 in buffer mode: bodies up to this size are read before responding
+	validateJSON   bool   // reject buffered application/json bodies that are not valid JSON
+	apiKeyHeader   string // named in the missing-key error
+	adminToken     string // guards GET /proxy/debug/headers; empty disables it
+
+	unavailable  config.UnavailableResponseConfig // custom response for upstream connectivity failures
+	exposeTiming bool                             // set X-Upstream-Duration-Ms
+	redactParams *regexp.Regexp                   // log.redact_query_params; nil when none are configured
+
+	streamProgressInterval time.Duration // debug progress logging of long streams; 0 disables
+
+	streams       sync.WaitGroup // response bodies currently being streamed
+	activeStreams atomic.Int64
 }
 
 // NewProxyHandler creates a ProxyHandler.
-func NewProxyHandler(svc *service.ProxyService, logger *slog.Logger) *ProxyHandler {
+// The metrics parameter is optional; pass nil to disable metrics recording.
+func NewProxyHandler(svc *service.ProxyService, cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, m *metrics.Metrics) *ProxyHandler {
+	strip := make(map[string]bool, len(cfg.Server.StripResponseHeaders))
+	for _, name := range cfg.Server.StripResponseHeaders {
+		strip[http.CanonicalHeaderKey(name)] = true
+	}
+	set := make(map[string]string, len(cfg.Server.SetResponseHeaders))
+	for name, val := range cfg.Server.SetResponseHeaders {
+		set[http.CanonicalHeaderKey(name)] = val
+	}
+
+	var bufferMax int64
+	if cfg.Server.ProxyMode == "buffer" {
+		bufferMax = cfg.Server.BufferMaxBytes
+	}
+
+	keyHeader := cfg.Vulners.APIKeyHeader
+	if keyHeader == "" {
+		keyHeader = config.UpstreamAPIKeyHeader
+	}
+
 	return &ProxyHandler{
-		service: svc,
-		logger:  logger.With("component", "proxy_handler"),
+		service:        svc,
+		logger:         logger.With("component", "proxy_handler"),
+		audit:          auditLog,
+		metrics:        m,
+		stripHeaders:   strip,
+		setHeaders:     set,
+		bufferMaxBytes: bufferMax,
+		validateJSON:   cfg.Server.ValidateJSONResponses,
+		apiKeyHeader:   keyHeader,
+		adminToken:     cfg.AdminToken(),
+		unavailable:    cfg.Server.UnavailableResponse,
+		exposeTiming:   cfg.Server.ExposeUpstreamTiming,
+		redactParams:   redactParamsPattern(cfg.Log.RedactQueryParams),
+
+		streamProgressInterval: cfg.Log.StreamProgressInterval.Std(),
 	}
 }
 
@@ -40,10 +110,13 @@ func (h *ProxyHandler) Handle(c echo.Context) error {
 	pr := &model.ProxyRequest{
 		Ctx:    req.Context(),
 		Method: req.Method,
+		Host:   req.Host,
 		Path:   req.URL.Path,
 		Query:  req.URL.Query(),
 		Header: req.Header,
 		Body:   req.Body,
+
+		ContentLength: req.ContentLength,
 	}
 
 	resp, err := h.service.Forward(pr)
@@ -52,21 +125,69 @@ func (h *ProxyHandler) Handle(c echo.Context) error {
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		h.audit.Record(c, audit.EventAuthRejected, "status", resp.StatusCode)
+	}
+
+	if h.bufferMaxBytes > 0 && req.Method != http.MethodHead {
+		if err := h.bufferBody(resp); errors.Is(err, errInvalidJSONBody) {
+			h.logger.Warn("upstream returned invalid JSON",
+				"status", resp.StatusCode,
+				"path", req.URL.Path,
+			)
+			return errorJSON(c, http.StatusBadGateway, "upstream returned an invalid JSON response")
+		} else if err != nil && clientGone(req) {
+			return h.clientDisconnected(c, metrics.DisconnectBeforeResponse, err)
+		} else if err != nil {
+			h.logger.Error("reading upstream response body",
+				"err", h.redactError(err),
+				"path", req.URL.Path,
+			)
+			return errorJSON(c, http.StatusBadGateway, "upstream response body could not be read")
+		}
+	}
+
 	// Copy filtered response headers
 	for key, vals := range resp.Header {
 		for _, v := range vals {
 			c.Response().Header().Add(key, v)
 		}
 	}
+	h.overrideResponseHeaders(c.Response().Header())
+	if h.exposeTiming {
+		c.Response().Header().Set("X-Upstream-Duration-Ms", strconv.FormatInt(resp.Duration.Milliseconds(), 10))
+	}
 
 	c.Response().WriteHeader(resp.StatusCode)
 
+	// HEAD responses carry the upstream headers (including Content-Length)
+	// but never a body.
+	if req.Method == http.MethodHead {
+		return nil
+	}
+
 	// Stream the upstream body directly to the client. If io.Copy fails
 	// mid-stream (e.g. client disconnect, network error), the HTTP status
 	// code has already been sent, so the client receives a truncated
 	// response with the original status. This is an inherent trade-off of
 	// streaming proxies — we log the error for observability.
-	if _, err := io.Copy(c.Response(), resp.Body); err != nil {
+	h.streams.Add(1)
+	h.activeStreams.Add(1)
+	defer func() {
+		h.activeStreams.Add(-1)
+		h.streams.Done()
+	}()
+
+	var body io.Reader = resp.Body
+	if h.streamProgressInterval > 0 {
+		progress := &middleware.CountingBody{ReadCloser: resp.Body}
+		body = progress
+		defer h.logStreamProgress(req.URL.Path, progress)()
+	}
+
+	if _, err := io.Copy(c.Response(), body); err != nil && clientGone(req) {
+		h.logDisconnect(req.URL.Path, metrics.DisconnectDuringResponse, err)
+	} else if err != nil {
 		h.logger.Error("streaming response body",
 			"err", err,
 			"path", req.URL.Path,
@@ -76,50 +197,269 @@ func (h *ProxyHandler) Handle(c echo.Context) error {
 	return nil
 }
 
+// logStreamProgress logs at debug level how far the body read through
+// progress has got, every streamProgressInterval, until the returned func is
+// called. A stream shorter than the interval is never logged.
+func (h *ProxyHandler) logStreamProgress(path string, progress *middleware.CountingBody) (stop func()) {
+	start := time.Now()
+	ticker := time.NewTicker(h.streamProgressInterval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.logger.Debug("streaming response body in progress",
+					"path", path,
+					"bytes_copied", progress.Count(),
+					"elapsed_ms", time.Since(start).Milliseconds(),
+				)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// errInvalidJSONBody is returned by bufferBody when server.validate_json_responses
+// is set and a buffered application/json body does not parse.
+var errInvalidJSONBody = errors.New("invalid JSON in upstream response body")
+
+// bufferBody reads the upstream body into memory so a failure surfaces before
+// any status is sent. Bodies larger than bufferMaxBytes are re-assembled and
+// streamed as usual.
+func (h *ProxyHandler) bufferBody(resp *model.ProxyResponse) error {
+	if cl, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && cl > h.bufferMaxBytes {
+		return nil
+	}
+
+	// Read one byte past the cap to detect oversized bodies without a
+	// Content-Length.
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, h.bufferMaxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(buf)) > h.bufferMaxBytes {
+		resp.Body = &bufferedBody{Reader: io.MultiReader(bytes.NewReader(buf), resp.Body), Closer: resp.Body}
+		return nil
+	}
+
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(buf), Closer: resp.Body}
+	resp.Header.Set("Content-Length", strconv.Itoa(len(buf)))
+	if h.validateJSON && len(buf) > 0 && isJSONContentType(resp.Header.Get("Content-Type")) && !json.Valid(buf) {
+		return errInvalidJSONBody
+	}
+	return nil
+}
+
+// isJSONContentType reports whether a Content-Type header names
+// application/json, ignoring parameters such as charset.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// bufferedBody pairs an in-memory body reader with the upstream body's Close.
+type bufferedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// ActiveStreams returns the number of upstream response bodies currently being
+// streamed to clients.
+func (h *ProxyHandler) ActiveStreams() int64 {
+	return h.activeStreams.Load()
+}
+
+// WaitStreams blocks until all in-flight response streams finish or ctx is
+// done. It returns the number of streams still active when it returned.
+func (h *ProxyHandler) WaitStreams(ctx context.Context) int64 {
+	done := make(chan struct{})
+	go func() {
+		h.streams.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+		return h.ActiveStreams()
+	}
+}
+
+// overrideResponseHeaders applies the configured strip and set rules to the
+// already-filtered upstream response headers. Stripping runs first, so a
+// header listed in both places ends up with the configured value.
+func (h *ProxyHandler) overrideResponseHeaders(header http.Header) {
+	for name := range h.stripHeaders {
+		header.Del(name)
+	}
+	for name, val := range h.setHeaders {
+		header.Set(name, val)
+	}
+}
+
+// clientGone reports whether the client has disconnected, which cancels the
+// inbound request context. A cancellation that starts on the upstream side
+// leaves that context intact.
+func clientGone(req *http.Request) bool {
+	return errors.Is(req.Context().Err(), context.Canceled)
+}
+
+// clientDisconnected handles a request whose client went away before the
+// response was sent. There is nobody to answer, so only the status is
+// recorded, for access logs and metrics, and no body is written.
+func (h *ProxyHandler) clientDisconnected(c echo.Context, phase string, err error) error {
+	h.logDisconnect(c.Request().URL.Path, phase, err)
+	return c.NoContent(statusClientClosedRequest)
+}
+
+// logDisconnect logs a client disconnect at info level and counts it.
+func (h *ProxyHandler) logDisconnect(path, phase string, err error) {
+	h.logger.Info("client disconnected",
+		"phase", phase,
+		"err", h.redactError(err),
+		"path", path,
+	)
+	if h.metrics != nil {
+		h.metrics.ClientDisconnects.WithLabelValues(phase).Inc()
+	}
+}
+
 func (h *ProxyHandler) mapError(c echo.Context, err error) error {
+	if errors.Is(err, context.Canceled) && clientGone(c.Request()) {
+		return h.clientDisconnected(c, metrics.DisconnectBeforeResponse, err)
+	}
+
+	// A malformed path or an oversized query is the client's doing, not a
+	// proxy fault, so it is not logged as an error.
+	if errors.Is(err, service.ErrInvalidRequestPath) || errors.Is(err, service.ErrUpstreamURLTooLong) {
+		h.logger.Info("rejected request",
+			"err", h.redactError(err),
+			"path", c.Request().URL.Path,
+		)
+		if errors.Is(err, service.ErrUpstreamURLTooLong) {
+			return errorJSON(c, http.StatusRequestURITooLong, "request URL too long")
+		}
+		return errorJSON(c, http.StatusBadRequest, "invalid request path")
+	}
+
 	h.logger.Error("proxy error",
-		"err", sanitizeError(err),
+		"err", h.redactError(err),
 		"path", c.Request().URL.Path,
 	)
 
 	if errors.Is(err, service.ErrMissingAPIKey) {
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "API key required: set api_key in config or send X-Api-Key header",
-		})
+		h.audit.Record(c, audit.EventAuthMissingKey)
+		return errorJSON(c, http.StatusUnauthorized, "API key required: set api_key in config or send "+h.apiKeyHeader+" header")
+	}
+
+	var paramsErr *service.MissingParamsError
+	if errors.As(err, &paramsErr) {
+		return errorJSON(c, http.StatusBadRequest, paramsErr.Error())
+	}
+
+	// The body limit middleware fails the read of an oversized chunked body
+	// while it is being streamed upstream.
+	if errors.Is(err, echo.ErrStatusRequestEntityTooLarge) {
+		return errorJSON(c, http.StatusRequestEntityTooLarge, "request body too large")
+	}
+
+	if errors.Is(err, service.ErrMissingContentType) || errors.Is(err, service.ErrUnexpectedBody) {
+		return errorJSON(c, http.StatusBadRequest, err.Error())
+	}
+
+	if errors.Is(err, service.ErrHeadersTooLarge) {
+		return errorJSON(c, http.StatusRequestHeaderFieldsTooLarge, err.Error())
+	}
+
+	var hostErr *service.HostNotAllowedError
+	if errors.As(err, &hostErr) {
+		return errorJSON(c, http.StatusForbidden, "upstream host not allowed")
+	}
+
+	if errors.Is(err, service.ErrUnexpectedRedirect) {
+		return errorJSON(c, http.StatusBadGateway, err.Error())
+	}
+
+	if errors.Is(err, service.ErrInvalidUpstreamURL) {
+		return errorJSON(c, http.StatusInternalServerError, "proxy could not build a valid upstream URL")
+	}
+
+	if errors.Is(err, client.ErrQueueFull) || errors.Is(err, client.ErrQueueTimeout) {
+		return errorJSON(c, http.StatusServiceUnavailable, "proxy is busy: no upstream slot became available, retry later")
 	}
 
 	if errors.Is(err, context.DeadlineExceeded) {
-		return c.JSON(http.StatusGatewayTimeout, map[string]string{
-			"error": "upstream request timed out",
-		})
+		return h.unavailableJSON(c, http.StatusGatewayTimeout, "upstream request timed out")
 	}
 
+	// The client is still connected, so the cancellation came from the
+	// upstream side.
 	if errors.Is(err, context.Canceled) {
-		return c.JSON(http.StatusBadGateway, map[string]string{
-			"error": "client disconnected",
-		})
+		return errorJSON(c, http.StatusBadGateway, "upstream request canceled")
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return h.unavailableJSON(c, http.StatusGatewayTimeout, "upstream request timed out")
 	}
 
 	var dnsErr *net.DNSError
 	if errors.As(err, &dnsErr) {
-		return c.JSON(http.StatusBadGateway, map[string]string{
-			"error": "upstream host unreachable",
-		})
+		return h.unavailableJSON(c, http.StatusBadGateway, "upstream host unreachable")
 	}
 
 	var urlErr *url.Error
 	if errors.As(err, &urlErr) {
-		return c.JSON(http.StatusBadGateway, map[string]string{
-			"error": "upstream connection failed",
-		})
+		return h.unavailableJSON(c, http.StatusBadGateway, "upstream connection failed")
 	}
 
-	return c.JSON(http.StatusBadGateway, map[string]string{
-		"error": "upstream request failed",
-	})
+	return errorJSON(c, http.StatusBadGateway, "upstream request failed")
+}
+
+// unavailableJSON writes the response for an upstream connectivity failure,
+// applying server.unavailable_response over the default code and message.
+func (h *ProxyHandler) unavailableJSON(c echo.Context, code int, message string) error {
+	if h.unavailable.StatusCode != 0 {
+		code = h.unavailable.StatusCode
+	}
+	if h.unavailable.Body == "" {
+		return errorJSON(c, code, message)
+	}
+	// The request ID may come from the client, so it is escaped before
+	// being spliced into the operator's JSON.
+	id, _ := json.Marshal(c.Response().Header().Get(echo.HeaderXRequestID))
+	body := strings.ReplaceAll(h.unavailable.Body, "{request_id}", string(id[1:len(id)-1]))
+	return c.JSONBlob(code, []byte(body))
 }
 
 // sanitizeError redacts API keys from error messages that may contain upstream URLs.
 func sanitizeError(err error) string {
 	return apiKeyPattern.ReplaceAllString(err.Error(), "${1}[REDACTED]")
 }
+
+// redactError is sanitizeError plus redaction of the values of the
+// configured log.redact_query_params.
+func (h *ProxyHandler) redactError(err error) string {
+	msg := sanitizeError(err)
+	if h.redactParams != nil {
+		msg = h.redactParams.ReplaceAllString(msg, "${1}[REDACTED]")
+	}
+	return msg
+}
+
+// redactParamsPattern returns a pattern matching the values of the named
+// query parameters in URLs, or nil when names is empty.
+func redactParamsPattern(names []string) *regexp.Regexp {
+	if len(names) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = regexp.QuoteMeta(n)
+	}
+	return regexp.MustCompile(`(?i)([?&](?:` + strings.Join(quoted, "|") + `)=)[^&\s"]+`)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,13 +12,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
 
+	"vulners-proxy-go/internal/audit"
 	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
 	"vulners-proxy-go/internal/service"
 )
 
@@ -38,7 +47,7 @@ func TestProxyHandler_Handle_ConfigAPIKey(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: "config-key"},
 		Upstream: config.UpstreamConfig{
 			BaseURL:         upstream.URL,
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -48,7 +57,7 @@ func TestProxyHandler_Handle_ConfigAPIKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewProxyService: %v", err)
 	}
-	h := NewProxyHandler(svc, logger)
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
@@ -90,7 +99,7 @@ func TestProxyHandler_Handle_HeaderAPIKey(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: ""}, // no config key
 		Upstream: config.UpstreamConfig{
 			BaseURL:         upstream.URL,
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -100,7 +109,7 @@ func TestProxyHandler_Handle_HeaderAPIKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewProxyService: %v", err)
 	}
-	h := NewProxyHandler(svc, logger)
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
@@ -122,7 +131,7 @@ func TestProxyHandler_Handle_MissingAPIKey(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: ""}, // no config key
 		Upstream: config.UpstreamConfig{
 			BaseURL:         "https://vulners.com",
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -132,7 +141,7 @@ func TestProxyHandler_Handle_MissingAPIKey(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewProxyService: %v", err)
 	}
-	h := NewProxyHandler(svc, logger)
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
@@ -173,7 +182,7 @@ func TestProxyHandler_Handle_POST(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: "test-key"},
 		Upstream: config.UpstreamConfig{
 			BaseURL:         upstream.URL,
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -183,7 +192,7 @@ func TestProxyHandler_Handle_POST(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewProxyService: %v", err)
 	}
-	h := NewProxyHandler(svc, logger)
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
 
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/api/v3/search/lucene/", strings.NewReader("hello"))
@@ -200,6 +209,340 @@ func TestProxyHandler_Handle_POST(t *testing.T) {
 	}
 }
 
+func TestProxyHandler_Handle_ExpectContinue(t *testing.T) {
+	payload := strings.Repeat("x", 64*1024)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != payload {
+			t.Errorf("upstream body length = %d, want %d", len(body), len(payload))
+		}
+		if r.ContentLength != int64(len(payload)) {
+			t.Errorf("upstream ContentLength = %d, want %d", r.ContentLength, len(payload))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"result":"ok"}`))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	e := echo.New()
+	e.POST("/api/v3/*", h.Handle)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	// Drive the exchange by hand: the client must see 100 Continue before it
+	// sends the body, then the proxied response.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = fmt.Fprintf(conn, "POST /api/v3/audit/audit/ HTTP/1.1\r\nHost: proxy\r\n"+
+		"Content-Type: application/json\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", len(payload))
+	if err != nil {
+		t.Fatalf("write headers: %v", err)
+	}
+
+	br := bufio.NewReader(conn)
+	interim, err := br.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read interim response: %v", err)
+	}
+	if !strings.HasPrefix(interim, "HTTP/1.1 100") {
+		t.Fatalf("interim response = %q, want 100 Continue", interim)
+	}
+	if _, err := br.ReadString('\n'); err != nil { // blank line ending the 1xx
+		t.Fatalf("read interim terminator: %v", err)
+	}
+
+	if _, err := io.WriteString(conn, payload); err != nil {
+		t.Fatalf("write body: %v", err)
+	}
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"result":"ok"}` {
+		t.Errorf("body = %q, want %q", body, `{"result":"ok"}`)
+	}
+}
+
+func TestProxyHandler_Handle_HEAD(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %q, want HEAD", r.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "42")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodHead, "/api/v3/search/lucene/?query=test", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := h.Handle(c); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if v := rec.Header().Get("Content-Type"); v != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", v, "application/json")
+	}
+	if v := rec.Header().Get("Content-Length"); v != "42" {
+		t.Errorf("Content-Length = %q, want %q", v, "42")
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body length = %d, want 0", rec.Body.Len())
+	}
+}
+
+func TestProxyHandler_Handle_BufferMode(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v3/broken/" {
+			// Promise more than is sent, then drop the connection.
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"partial":`))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"result":"ok"}`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		mode       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"buffer ok", "buffer", "/api/v3/search/lucene/", http.StatusOK, `{"result":"ok"}`},
+		{"buffer mid-body failure", "buffer", "/api/v3/broken/", http.StatusBadGateway, ""},
+		{"stream mid-body failure", "stream", "/api/v3/broken/", http.StatusOK, `{"partial":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Server:  config.ServerConfig{ProxyMode: tt.mode, BufferMaxBytes: 1024},
+				Vulners: config.VulnersConfig{APIKey: "test-key"},
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					Timeout:         config.Duration(10 * time.Second),
+					IdleConnections: 10,
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			vc := client.NewVulnersClient(cfg, logger, nil)
+			svc, err := newTestProxyService(vc, cfg, logger)
+			if err != nil {
+				t.Fatalf("NewProxyService: %v", err)
+			}
+			h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := h.Handle(c); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusOK && tt.mode == "buffer" {
+				if v := rec.Header().Get("Content-Length"); v != strconv.Itoa(len(tt.wantBody)) {
+					t.Errorf("Content-Length = %q, want %d", v, len(tt.wantBody))
+				}
+			}
+		})
+	}
+}
+
+func TestProxyHandler_Handle_ValidateJSONResponses(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/html/":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`<html><script>alert(1)</script></html>`))
+		case "/api/v3/text/":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`not json`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"result":"ok"}`))
+		}
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		mode       string
+		validate   bool
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"valid JSON", "buffer", true, "/api/v3/search/lucene/", http.StatusOK, `{"result":"ok"}`},
+		{"malformed JSON rejected", "buffer", true, "/api/v3/html/", http.StatusBadGateway, ""},
+		{"non-JSON content type relayed", "buffer", true, "/api/v3/text/", http.StatusOK, `not json`},
+		{"malformed JSON relayed when disabled", "buffer", false, "/api/v3/html/", http.StatusOK, `<html><script>alert(1)</script></html>`},
+		{"no-op in stream mode", "stream", true, "/api/v3/html/", http.StatusOK, `<html><script>alert(1)</script></html>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Server: config.ServerConfig{
+					ProxyMode:             tt.mode,
+					BufferMaxBytes:        1024,
+					ValidateJSONResponses: tt.validate,
+				},
+				Vulners: config.VulnersConfig{APIKey: "test-key"},
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					Timeout:         config.Duration(10 * time.Second),
+					IdleConnections: 10,
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			vc := client.NewVulnersClient(cfg, logger, nil)
+			svc, err := newTestProxyService(vc, cfg, logger)
+			if err != nil {
+				t.Fatalf("NewProxyService: %v", err)
+			}
+			h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, http.NoBody)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.Handle(c); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusBadGateway && strings.Contains(rec.Body.String(), "<html>") {
+				t.Errorf("body = %q, must not relay the upstream body", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProxyHandler_WaitStreams(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/archive/collection/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handled := make(chan struct{})
+	go func() {
+		_ = h.Handle(c)
+		close(handled)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for h.ActiveStreams() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if n := h.WaitStreams(ctx); n != 1 {
+		t.Errorf("WaitStreams() at deadline = %d, want 1", n)
+	}
+
+	close(release)
+	<-handled
+
+	if n := h.WaitStreams(context.Background()); n != 0 {
+		t.Errorf("WaitStreams() after completion = %d, want 0", n)
+	}
+}
+
 func TestProxyHandler_Handle_CanceledContext(t *testing.T) {
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Wait until client context is done.
@@ -212,7 +555,340 @@ func TestProxyHandler_Handle_CanceledContext(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: "test-key"},
 		Upstream: config.UpstreamConfig{
 			BaseURL:         upstream.URL,
-			TimeoutSeconds:  30,
+			Timeout:         config.Duration(30 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	m := metrics.New()
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, m)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
+	// Create a pre-canceled context to simulate client disconnect.
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := h.Handle(c); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if rec.Code != statusClientClosedRequest {
+		t.Errorf("status = %d, want %d", rec.Code, statusClientClosedRequest)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("body = %q, want none", rec.Body.String())
+	}
+	if strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("client disconnect logged as an error:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "client disconnected") {
+		t.Errorf("logs missing client disconnect:\n%s", logs.String())
+	}
+	if got := clientDisconnects(t, m); got[metrics.DisconnectBeforeResponse] != 1 {
+		t.Errorf("client disconnects = %v, want 1 before_response", got)
+	}
+}
+
+func TestProxyHandler_mapError_UpstreamCanceled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := metrics.New()
+	h := &ProxyHandler{logger: logger, metrics: m}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// The client is still connected; the cancellation came from elsewhere.
+	wrapped := fmt.Errorf("forward to upstream: %w", context.Canceled)
+
+	if err := h.mapError(c, wrapped); err != nil {
+		t.Fatalf("mapError() returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if got := clientDisconnects(t, m); len(got) != 0 {
+		t.Errorf("client disconnects = %v, want none", got)
+	}
+}
+
+// clientDisconnects returns vulners_proxy_client_disconnects_total by phase.
+func clientDisconnects(t *testing.T, m *metrics.Metrics) map[string]float64 {
+	t.Helper()
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "vulners_proxy_client_disconnects_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			got[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+		}
+	}
+	return got
+}
+
+func TestProxyHandler_Handle_ResponseHeaderOverrides(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Date", "Mon, 01 Jan 2025 00:00:00 GMT")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			StripResponseHeaders: []string{"date"},
+			SetResponseHeaders:   map[string]string{"cache-control": "no-store"},
+		},
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := h.Handle(c); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if v := rec.Header().Get("Date"); v != "" {
+		t.Errorf("Date should be stripped, got %q", v)
+	}
+	if v := rec.Header().Get("Cache-Control"); v != "no-store" {
+		t.Errorf("Cache-Control = %q, want %q", v, "no-store")
+	}
+	if v := rec.Header().Get("Content-Type"); v != "application/json" {
+		t.Errorf("Content-Type = %q, want %q", v, "application/json")
+	}
+}
+
+func TestProxyHandler_Handle_ExposeUpstreamTiming(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	for _, expose := range []bool{false, true} {
+		cfg := &config.Config{
+			Server:  config.ServerConfig{ExposeUpstreamTiming: expose},
+			Vulners: config.VulnersConfig{APIKey: "test-key"},
+			Upstream: config.UpstreamConfig{
+				BaseURL:         upstream.URL,
+				Timeout:         config.Duration(10 * time.Second),
+				IdleConnections: 10,
+			},
+		}
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		svc, err := newTestProxyService(client.NewVulnersClient(cfg, logger, nil), cfg, logger)
+		if err != nil {
+			t.Fatalf("NewProxyService: %v", err)
+		}
+		h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+		rec := httptest.NewRecorder()
+		if err := h.Handle(echo.New().NewContext(req, rec)); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+
+		got := rec.Header().Get("X-Upstream-Duration-Ms")
+		if !expose {
+			if got != "" {
+				t.Errorf("X-Upstream-Duration-Ms = %q with timing disabled, want absent", got)
+			}
+			continue
+		}
+		if ms, err := strconv.Atoi(got); err != nil || ms < 20 {
+			t.Errorf("X-Upstream-Duration-Ms = %q, want at least 20", got)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestProxyHandler_Handle_StreamProgressLogging(t *testing.T) {
+	tests := []struct {
+		name    string
+		pause   time.Duration // between the two body chunks
+		wantLog bool
+	}{
+		{"long stream logged", 150 * time.Millisecond, true},
+		{"short stream not logged", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("first chunk"))
+				w.(http.Flusher).Flush()
+				time.Sleep(tt.pause)
+				_, _ = w.Write([]byte("second chunk"))
+			}))
+			defer upstream.Close()
+
+			cfg := &config.Config{
+				Vulners: config.VulnersConfig{APIKey: "test-key"},
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					Timeout:         config.Duration(10 * time.Second),
+					IdleConnections: 10,
+				},
+				Log: config.LogConfig{StreamProgressInterval: config.Duration(50 * time.Millisecond)},
+			}
+			var logs syncBuffer
+			logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			svc, err := newTestProxyService(client.NewVulnersClient(cfg, logger, nil), cfg, logger)
+			if err != nil {
+				t.Fatalf("NewProxyService: %v", err)
+			}
+			h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/archive/collection/", http.NoBody)
+			rec := httptest.NewRecorder()
+			if err := h.Handle(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if rec.Body.String() != "first chunksecond chunk" {
+				t.Errorf("body = %q, want %q", rec.Body.String(), "first chunksecond chunk")
+			}
+
+			out := logs.String()
+			if got := strings.Contains(out, "streaming response body in progress"); got != tt.wantLog {
+				t.Errorf("progress logged = %v, want %v; logs:\n%s", got, tt.wantLog, out)
+			}
+			if tt.wantLog && !strings.Contains(out, "bytes_copied=11") {
+				t.Errorf("progress log lacks bytes_copied=11; logs:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_Handle_ChunkedBody(t *testing.T) {
+	var gotBody string
+	var gotLength int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		gotBody, gotLength = string(b), r.ContentLength
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc, err := newTestProxyService(client.NewVulnersClient(cfg, logger, nil), cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler(logger)
+	e.Use(echomw.BodyLimit("1KB"))
+	e.POST("/api/v3/*", h.Handle)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"within limit is streamed", `{"query":"` + strings.Repeat("a", 500) + `"}`, http.StatusOK},
+		{"over limit is rejected", `{"query":"` + strings.Repeat("a", 4096) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody, gotLength = "", 0
+			// Hide the reader's length so the request goes out chunked.
+			req := httptest.NewRequest(http.MethodPost, "/api/v3/search/lucene/", io.MultiReader(strings.NewReader(tt.body)))
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if gotBody != tt.body {
+				t.Errorf("upstream body length = %d, want %d", len(gotBody), len(tt.body))
+			}
+			if gotLength != -1 {
+				t.Errorf("upstream ContentLength = %d, want -1 (chunked)", gotLength)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_Handle_MissingRequiredParams(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			RequiredParams: map[string][]string{"/api/v3/search/lucene/": {"query", "size"}},
+		},
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         "https://vulners.com",
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
@@ -222,14 +898,10 @@ func TestProxyHandler_Handle_CanceledContext(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewProxyService: %v", err)
 	}
-	h := NewProxyHandler(svc, logger)
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
 
 	e := echo.New()
-	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
-	// Create a pre-canceled context to simulate client disconnect.
-	ctx, cancel := context.WithCancel(req.Context())
-	cancel()
-	req = req.WithContext(ctx)
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?size=10", http.NoBody)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
@@ -237,9 +909,78 @@ func TestProxyHandler_Handle_CanceledContext(t *testing.T) {
 		t.Fatalf("Handle() error = %v", err)
 	}
 
-	// Should get a 502/504 error response, not 200.
-	if rec.Code == http.StatusOK {
-		t.Error("expected non-200 status for canceled context")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body["error"] != "missing required query parameters: query" {
+		t.Errorf("error = %q, want %q", body["error"], "missing required query parameters: query")
+	}
+}
+
+func TestProxyHandler_Handle_RequireContentType(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		Server:  config.ServerConfig{RequireContentTypeOnPost: true},
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         upstream.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	vc := client.NewVulnersClient(cfg, logger, nil)
+	svc, err := newTestProxyService(vc, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewProxyService: %v", err)
+	}
+	h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		wantStatus  int
+	}{
+		{"POST without Content-Type", http.MethodPost, "", http.StatusBadRequest},
+		{"PATCH without Content-Type", http.MethodPatch, "", http.StatusBadRequest},
+		{"POST with Content-Type", http.MethodPost, "application/json", http.StatusOK},
+		{"GET without Content-Type", http.MethodGet, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v3/search/lucene/", strings.NewReader(`{"query":"test"}`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.Handle(c); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusBadRequest {
+				var body map[string]string
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				if !strings.Contains(body["error"], "Content-Type") {
+					t.Errorf("error = %q, want it to mention Content-Type", body["error"])
+				}
+			}
+		})
 	}
 }
 
@@ -299,6 +1040,201 @@ func TestProxyHandler_mapError_URLError(t *testing.T) {
 	}
 }
 
+func TestProxyHandler_mapError_QueueTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &ProxyHandler{logger: logger}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	wrapped := fmt.Errorf("forward to upstream: %w", client.ErrQueueTimeout)
+
+	if err := h.mapError(c, wrapped); err != nil {
+		t.Fatalf("mapError() returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestProxyHandler_mapError_InvalidUpstreamURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantError bool // logged at ERROR
+	}{
+		{"host mismatch", fmt.Errorf("%w: host %q does not match upstream %q", service.ErrInvalidUpstreamURL, "evil.com", "vulners.com"), http.StatusInternalServerError, true},
+		{"dot segments", fmt.Errorf("%w: path %q contains dot segments", service.ErrInvalidRequestPath, "/a/../b"), http.StatusBadRequest, false},
+		{"control characters", fmt.Errorf("%w: path contains control characters", service.ErrInvalidRequestPath), http.StatusBadRequest, false},
+		{"too long", fmt.Errorf("%w: length 9000 exceeds 8192", service.ErrUpstreamURLTooLong), http.StatusRequestURITooLong, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			h := &ProxyHandler{logger: slog.New(slog.NewTextHandler(&logs, nil))}
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if err := h.mapError(c, fmt.Errorf("build upstream URL: %w", tt.err)); err != nil {
+				t.Fatalf("mapError() returned error: %v", err)
+			}
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := strings.Contains(logs.String(), "level=ERROR"); got != tt.wantError {
+				t.Errorf("logged at ERROR = %v, want %v; logs: %s", got, tt.wantError, logs.String())
+			}
+		})
+	}
+}
+
+func TestProxyHandler_mapError_UnexpectedRedirect(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &ProxyHandler{logger: logger}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	if err := h.mapError(c, service.ErrUnexpectedRedirect); err != nil {
+		t.Fatalf("mapError() returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	if !strings.Contains(rec.Body.String(), "unexpected upstream redirect") {
+		t.Errorf("body = %q, want it to mention the redirect", rec.Body.String())
+	}
+}
+
+func TestProxyHandler_mapError_HostNotAllowed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &ProxyHandler{logger: logger}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	wrapped := fmt.Errorf("route request: %w", &service.HostNotAllowedError{Host: "evil.com"})
+	if err := h.mapError(c, wrapped); err != nil {
+		t.Fatalf("mapError() returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body["error"] != "upstream host not allowed" {
+		t.Errorf("error = %q, want %q", body["error"], "upstream host not allowed")
+	}
+}
+
+func TestProxyHandler_mapError_NetTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &ProxyHandler{logger: logger}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	urlErr := &url.Error{Op: "Get", URL: "https://vulners.com/api", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}
+	wrapped := fmt.Errorf("forward to upstream: %w", urlErr)
+
+	if err := h.mapError(c, wrapped); err != nil {
+		t.Fatalf("mapError() returned error: %v", err)
+	}
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestProxyHandler_mapError_UnavailableResponse(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	connErr := &url.Error{Op: "Get", URL: "https://vulners.com/api", Err: fmt.Errorf("connection refused")}
+
+	tests := []struct {
+		name        string
+		unavailable config.UnavailableResponseConfig
+		err         error
+		wantCode    int
+		wantBody    string
+	}{
+		{
+			name:     "default",
+			err:      connErr,
+			wantCode: http.StatusBadGateway,
+			wantBody: `"error":"upstream connection failed"`,
+		},
+		{
+			name:        "status only",
+			unavailable: config.UnavailableResponseConfig{StatusCode: http.StatusServiceUnavailable},
+			err:         connErr,
+			wantCode:    http.StatusServiceUnavailable,
+			wantBody:    `"error":"upstream connection failed"`,
+		},
+		{
+			name: "custom body with escaped request ID",
+			unavailable: config.UnavailableResponseConfig{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       `{"message":"try later","support":"https://example.com/help","id":"{request_id}"}`,
+			},
+			err:      &net.DNSError{Err: "no such host", Name: "vulners.com"},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `"id":"req-\"1\""`,
+		},
+		{
+			name: "not applied to queue rejections",
+			unavailable: config.UnavailableResponseConfig{
+				StatusCode: http.StatusServiceUnavailable,
+				Body:       `{"message":"try later"}`,
+			},
+			err:      client.ErrQueueFull,
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `"error":"proxy is busy`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &ProxyHandler{logger: logger, unavailable: tt.unavailable}
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/", http.NoBody)
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			c.Response().Header().Set(echo.HeaderXRequestID, `req-"1"`)
+
+			if err := h.mapError(c, fmt.Errorf("forward to upstream: %w", tt.err)); err != nil {
+				t.Fatalf("mapError() returned error: %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Fatalf("body is not valid JSON: %s", rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
 func TestSanitizeError(t *testing.T) {
 	tests := []struct {
 		name string
@@ -332,7 +1268,169 @@ func TestSanitizeError(t *testing.T) {
 	}
 }
 
+func TestProxyHandler_redactError(t *testing.T) {
+	h := &ProxyHandler{redactParams: redactParamsPattern([]string{"email", "user.name"})}
+
+	tests := []struct {
+		name string
+		err  string
+		want string
+	}{
+		{
+			name: "redacts configured param and apiKey",
+			err:  `Get "https://vulners.com/api/v3/search?apiKey=secret&email=a@b.c&query=x": EOF`,
+			want: `Get "https://vulners.com/api/v3/search?apiKey=[REDACTED]&email=[REDACTED]&query=x": EOF`,
+		},
+		{
+			name: "case-insensitive, first param",
+			err:  `Get "https://vulners.com/api?EMAIL=a@b.c": EOF`,
+			want: `Get "https://vulners.com/api?EMAIL=[REDACTED]": EOF`,
+		},
+		{
+			name: "name is matched literally",
+			err:  `Get "https://vulners.com/api?user.name=bob&userXname=alice": EOF`,
+			want: `Get "https://vulners.com/api?user.name=[REDACTED]&userXname=alice": EOF`,
+		},
+		{
+			name: "suffix of another param untouched",
+			err:  `Get "https://vulners.com/api?notify_email=x": EOF`,
+			want: `Get "https://vulners.com/api?notify_email=x": EOF`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.redactError(fmt.Errorf("%s", tt.err)); got != tt.want {
+				t.Errorf("redactError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // newTestProxyService creates a ProxyService that accepts any upstream host (for httptest).
 func newTestProxyService(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger) (*service.ProxyService, error) {
-	return service.NewProxyServiceForTest(c, cfg, logger)
+	return service.NewProxyServiceForTest(c, cfg, logger, nil)
+}
+
+func TestProxyHandler_Handle_AuditEvents(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		wantEvent string
+	}{
+		{"missing key", "", audit.EventAuthMissingKey},
+		{"upstream rejection", "client-secret-key", audit.EventAuthRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					Timeout:         config.Duration(10 * time.Second),
+					IdleConnections: 10,
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			vc := client.NewVulnersClient(cfg, logger, nil)
+			svc, err := newTestProxyService(vc, cfg, logger)
+			if err != nil {
+				t.Fatalf("NewProxyService: %v", err)
+			}
+			var buf strings.Builder
+			h := NewProxyHandler(svc, cfg, logger, audit.New(&buf, "json"), nil)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?query=test", http.NoBody)
+			if tt.apiKey != "" {
+				req.Header.Set("X-Api-Key", tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			rec.Header().Set(echo.HeaderXRequestID, "req-1")
+			c := e.NewContext(req, rec)
+
+			if err := h.Handle(c); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+				t.Fatalf("unmarshal audit entry %q: %v", buf.String(), err)
+			}
+			if entry["event"] != tt.wantEvent {
+				t.Errorf("event = %v, want %q", entry["event"], tt.wantEvent)
+			}
+			if entry["request_id"] != "req-1" {
+				t.Errorf("request_id = %v, want %q", entry["request_id"], "req-1")
+			}
+			if strings.Contains(buf.String(), "client-secret-key") {
+				t.Error("audit entry must not contain the API key")
+			}
+		})
+	}
+}
+
+func TestProxyHandler_Handle_RejectGetBody(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		reject     bool
+		method     string
+		body       string
+		wantStatus int
+		wantHit    bool
+	}{
+		{"GET with body rejected", true, http.MethodGet, `{"query":"test"}`, http.StatusBadRequest, false},
+		{"DELETE with body rejected", true, http.MethodDelete, `{"id":1}`, http.StatusBadRequest, false},
+		{"GET without body allowed", true, http.MethodGet, "", http.StatusOK, true},
+		{"POST with body allowed", true, http.MethodPost, `{"query":"test"}`, http.StatusOK, true},
+		{"GET with body forwarded when disabled", false, http.MethodGet, `{"query":"test"}`, http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Server:  config.ServerConfig{RejectGetBody: tt.reject},
+				Vulners: config.VulnersConfig{APIKey: "test-key"},
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					Timeout:         config.Duration(10 * time.Second),
+					IdleConnections: 10,
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			vc := client.NewVulnersClient(cfg, logger, nil)
+			svc, err := newTestProxyService(vc, cfg, logger)
+			if err != nil {
+				t.Fatalf("NewProxyService: %v", err)
+			}
+			h := NewProxyHandler(svc, cfg, logger, nil, nil)
+
+			hits.Store(0)
+			req := httptest.NewRequest(tt.method, "/api/v3/search/lucene/", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.Handle(c); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := hits.Load() > 0; got != tt.wantHit {
+				t.Errorf("upstream hit = %v, want %v", got, tt.wantHit)
+			}
+		})
+	}
 }
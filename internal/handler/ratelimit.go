@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/audit"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/middleware"
+)
+
+// RateLimitHandler changes the per-IP rate limit at runtime, e.g. to tighten
+// it on one instance during an incident without a restart. It is exposed only
+// when rate limiting is enabled and admin.token is set.
+type RateLimitHandler struct {
+	adminToken string
+
+	logger *slog.Logger
+	audit  *audit.Logger               // nil when auditing is disabled
+	store  *middleware.AdjustableStore // nil when rate limiting is disabled
+}
+
+// NewRateLimitHandler creates a RateLimitHandler.
+func NewRateLimitHandler(cfg *config.Config, logger *slog.Logger, auditLog *audit.Logger, store *middleware.AdjustableStore) *RateLimitHandler {
+	return &RateLimitHandler{
+		adminToken: cfg.AdminToken(),
+		logger:     logger.With("component", "rate_limit"),
+		audit:      auditLog,
+		store:      store,
+	}
+}
+
+// Enabled reports whether the adjustment endpoint should be exposed.
+func (h *RateLimitHandler) Enabled() bool {
+	return h.store != nil && h.adminToken != ""
+}
+
+// Update handles PUT /proxy/ratelimit with a JSON body
+// {"requests_per_second": n}. The caller must send the admin token as
+// "Authorization: Bearer <token>". The change lasts until the next restart.
+func (h *RateLimitHandler) Update(c echo.Context) error {
+	if !bearerTokenValid(c.Request().Header.Get(echo.HeaderAuthorization), h.adminToken) {
+		h.audit.Record(c, audit.EventAdminDenied)
+		return errorJSON(c, http.StatusUnauthorized, "valid admin token required")
+	}
+
+	var body struct {
+		RequestsPerSecond *float64 `json:"requests_per_second"`
+	}
+	if err := c.Bind(&body); err != nil || body.RequestsPerSecond == nil ||
+		*body.RequestsPerSecond <= 0 || math.IsInf(*body.RequestsPerSecond, 0) || math.IsNaN(*body.RequestsPerSecond) {
+		return errorJSON(c, http.StatusBadRequest, `request body must be JSON of the form {"requests_per_second": n} with n > 0`)
+	}
+
+	previous := h.store.Rate()
+	h.store.SetRate(*body.RequestsPerSecond)
+	h.logger.Warn("rate limit changed", "previous_rps", previous, "rps", *body.RequestsPerSecond)
+	h.audit.Record(c, audit.EventRateLimitSet, "requests_per_second", *body.RequestsPerSecond)
+	return c.JSON(http.StatusOK, map[string]float64{"requests_per_second": h.store.Rate()})
+}
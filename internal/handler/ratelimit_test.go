@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/middleware"
+)
+
+func newTestRateLimitStore(rps float64) *middleware.AdjustableStore {
+	return middleware.NewAdjustableStore(rps, func(rps float64) echomw.RateLimiterStore {
+		return middleware.NewSlidingWindowStore(rps)
+	})
+}
+
+func TestRateLimitHandler_Enabled(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	withToken := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		store *middleware.AdjustableStore
+		want  bool
+	}{
+		{"enabled", withToken, newTestRateLimitStore(10), true},
+		{"rate limiting off", withToken, nil, false},
+		{"no admin token", &config.Config{}, newTestRateLimitStore(10), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRateLimitHandler(tt.cfg, logger, nil, tt.store).Enabled(); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitHandler_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     string
+		body     string
+		wantCode int
+		wantRate float64
+	}{
+		{"updates", "Bearer " + testAdminToken, `{"requests_per_second":2.5}`, http.StatusOK, 2.5},
+		{"missing token", "", `{"requests_per_second":2.5}`, http.StatusUnauthorized, 100},
+		{"wrong token", "Bearer wrong-token-000000", `{"requests_per_second":2.5}`, http.StatusUnauthorized, 100},
+		{"missing field", "Bearer " + testAdminToken, `{}`, http.StatusBadRequest, 100},
+		{"zero", "Bearer " + testAdminToken, `{"requests_per_second":0}`, http.StatusBadRequest, 100},
+		{"negative", "Bearer " + testAdminToken, `{"requests_per_second":-1}`, http.StatusBadRequest, 100},
+		{"not a number", "Bearer " + testAdminToken, `{"requests_per_second":"fast"}`, http.StatusBadRequest, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Maintenance: config.MaintenanceConfig{AdminToken: testAdminToken}}
+			store := newTestRateLimitStore(100)
+			h := NewRateLimitHandler(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil, store)
+
+			req := httptest.NewRequest(http.MethodPut, "/proxy/ratelimit", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tt.auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+
+			if err := h.Update(c); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := store.Rate(); got != tt.wantRate {
+				t.Errorf("Rate() = %v, want %v", got, tt.wantRate)
+			}
+
+			// /proxy/status reports the rate in effect.
+			rec = httptest.NewRecorder()
+			c = echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/proxy/status", http.NoBody), rec)
+			if err := NewHealthHandler(cfg, "test", nil, store, nil, nil).Status(c); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			var status struct {
+				RateLimitRPS float64 `json:"rate_limit_rps"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if status.RateLimitRPS != tt.wantRate {
+				t.Errorf("status rate_limit_rps = %v, want %v", status.RateLimitRPS, tt.wantRate)
+			}
+		})
+	}
+}
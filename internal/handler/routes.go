@@ -2,13 +2,33 @@ package handler
 
 import (
 	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/routes"
 )
 
-// RegisterRoutes wires all route handlers onto the Echo instance.
-func RegisterRoutes(e *echo.Echo, proxy *ProxyHandler, health *HealthHandler) {
-	e.GET("/healthz", health.Healthz)
-	e.GET("/proxy/status", health.Status)
+// RegisterRoutes wires all route handlers onto the Echo instance. Paths come
+// from the routes package, which config validation also uses to keep
+// configurable paths from shadowing them.
+func RegisterRoutes(e *echo.Echo, proxy *ProxyHandler, health *HealthHandler, maint *MaintenanceHandler, metricsJSON *MetricsJSONHandler, rateLimit *RateLimitHandler, inFlight *InFlightHandler) {
+	e.GET(routes.Healthz, health.Healthz)
+	e.GET(routes.Status, health.Status)
+	e.GET(routes.AllowedHosts, health.AllowedHosts)
+	if maint.AdminEnabled() {
+		e.POST(routes.Maintenance, maint.Toggle)
+	}
+	if metricsJSON.Enabled() {
+		e.GET(routes.MetricsJSON, metricsJSON.Snapshot)
+	}
+	if rateLimit.Enabled() {
+		e.PUT(routes.RateLimit, rateLimit.Update)
+	}
+	if inFlight.Enabled() {
+		e.GET(routes.InFlight, inFlight.Snapshot)
+	}
+	if proxy.DebugEnabled() {
+		e.GET(routes.DebugHeaders, proxy.DebugHeaders)
+	}
 
-	e.Any("/api/v3/*", proxy.Handle)
-	e.Any("/api/v4/*", proxy.Handle)
+	e.Any(routes.APIv3+"/*", proxy.Handle, maint.Guard)
+	e.Any(routes.APIv4+"/*", proxy.Handle, maint.Guard)
 }
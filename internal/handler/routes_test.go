@@ -5,12 +5,18 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
 
 	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+	"vulners-proxy-go/internal/middleware"
+	"vulners-proxy-go/internal/routes"
 	"vulners-proxy-go/internal/service"
 )
 
@@ -25,22 +31,23 @@ func TestRegisterRoutes_Wiring(t *testing.T) {
 		Vulners: config.VulnersConfig{APIKey: "test-key"},
 		Upstream: config.UpstreamConfig{
 			BaseURL:         upstream.URL,
-			TimeoutSeconds:  10,
+			Timeout:         config.Duration(10 * time.Second),
 			IdleConnections: 10,
 		},
 	}
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	vc := client.NewVulnersClient(cfg, logger, nil)
-	svc, err := service.NewProxyServiceForTest(vc, cfg, logger)
+	svc, err := service.NewProxyServiceForTest(vc, cfg, logger, nil)
 	if err != nil {
 		t.Fatalf("NewProxyServiceForTest: %v", err)
 	}
 
-	proxy := NewProxyHandler(svc, logger)
-	health := NewHealthHandler(cfg, "test")
+	proxy := NewProxyHandler(svc, cfg, logger, nil, nil)
+	health := NewHealthHandler(cfg, "test", nil, nil, nil, nil)
+	maint := NewMaintenanceHandler(cfg, logger, nil, nil)
 
 	e := echo.New()
-	RegisterRoutes(e, proxy, health)
+	RegisterRoutes(e, proxy, health, maint, NewMetricsJSONHandler(cfg, logger, nil, nil), NewRateLimitHandler(cfg, logger, nil, nil), NewInFlightHandler(cfg, nil, nil))
 
 	tests := []struct {
 		name       string
@@ -68,3 +75,35 @@ func TestRegisterRoutes_Wiring(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterRoutes_AllRoutesReserved(t *testing.T) {
+	cfg := &config.Config{
+		Upstream:    config.UpstreamConfig{BaseURL: "https://vulners.com", IdleConnections: 1},
+		Maintenance: config.MaintenanceConfig{AdminToken: "0123456789abcdef"},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc, err := service.NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, nil)
+	if err != nil {
+		t.Fatalf("NewProxyServiceForTest: %v", err)
+	}
+
+	e := echo.New()
+	RegisterRoutes(e, NewProxyHandler(svc, cfg, logger, nil, nil), NewHealthHandler(cfg, "test", nil, nil, nil, nil),
+		NewMaintenanceHandler(cfg, logger, nil, nil), NewMetricsJSONHandler(cfg, logger, nil, metrics.New()),
+		NewRateLimitHandler(cfg, logger, nil, middleware.NewAdjustableStore(1, func(float64) echomw.RateLimiterStore { return middleware.AllStores{} })),
+		NewInFlightHandler(cfg, nil, middleware.NewInFlightRegistry()))
+
+	// A route missing from ReservedPrefixes could be shadowed by metrics.path.
+	for _, r := range e.Routes() {
+		covered := false
+		for _, prefix := range routes.ReservedPrefixes() {
+			if r.Path == prefix || strings.HasPrefix(r.Path, prefix+"/") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			t.Errorf("route %s %s is not covered by routes.ReservedPrefixes()", r.Method, r.Path)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/service"
+)
+
+// upstreamCheckResult is the outcome of a live upstream check, reported as
+// "upstream_check" in GET /proxy/status?check=true.
+type upstreamCheckResult struct {
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checked_at"`
+	// Cached is set when the result was taken from an earlier check because
+	// status_check.min_interval had not yet passed.
+	Cached bool `json:"cached"`
+}
+
+// upstreamCheck runs the live upstream check for /proxy/status. Checks are
+// serialized and at most one runs per interval, so polling the endpoint
+// cannot turn the proxy into a load generator against upstream.
+type upstreamCheck struct {
+	svc      *service.ProxyService
+	path     string
+	timeout  time.Duration
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	last   upstreamCheckResult
+	lastAt time.Time // zero until the first check
+}
+
+func newUpstreamCheck(cfg *config.Config, svc *service.ProxyService) *upstreamCheck {
+	return &upstreamCheck{
+		svc:      svc,
+		path:     cfg.Startup.SelfTestPath,
+		timeout:  cfg.StatusCheck.Timeout.Std(),
+		interval: cfg.StatusCheck.MinInterval.Std(),
+		now:      time.Now,
+	}
+}
+
+// run checks upstream, or returns the previous result when the last check
+// is younger than the interval. The check is detached from ctx's
+// cancellation: a caller that hangs up must not leave a canceled result
+// behind for everyone else until the interval expires.
+func (u *upstreamCheck) run(ctx context.Context) upstreamCheckResult {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !u.lastAt.IsZero() && u.now().Sub(u.lastAt) < u.interval {
+		res := u.last
+		res.Cached = true
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), u.timeout)
+	defer cancel()
+
+	start := u.now()
+	err := u.svc.SelfTest(ctx, u.path)
+	res := upstreamCheckResult{
+		OK:        err == nil,
+		LatencyMs: u.now().Sub(start).Milliseconds(),
+		CheckedAt: start.UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		res.Error = sanitizeError(err)
+	}
+	u.last, u.lastAt = res, start
+	return res
+}
@@ -0,0 +1,144 @@
+// Package logfile provides a size-rotated log file for deployments where
+// stdout is not captured.
+package logfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is appended to the file name of rotated backups. It sorts
+// lexically in time order.
+const backupTimeFormat = "20060102T150405.000"
+
+// File is an io.WriteCloser that appends to a log file and rotates it once it
+// would grow past a size limit. Rotated files are renamed to
+// "<path>.<timestamp>"; backups beyond maxBackups or older than maxAge are
+// deleted after each rotation.
+type File struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	size int64
+
+	maxSize    int64         // bytes; 0 disables rotation
+	maxBackups int           // 0 keeps all backups
+	maxAge     time.Duration // 0 keeps backups regardless of age
+
+	now func() time.Time
+}
+
+// Open opens or creates the log file at path in append mode with mode 0600.
+func Open(path string, maxSizeMB, maxBackups, maxAgeDays int) (*File, error) {
+	lf := &File{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		now:        time.Now,
+	}
+	if err := lf.open(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+func (lf *File) open() error {
+	f, err := os.OpenFile(lf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("logfile: open %s: %w", lf.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("logfile: stat %s: %w", lf.path, err)
+	}
+	lf.f = f
+	lf.size = info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating first if p would push the file past
+// the size limit. A single write larger than the limit is still written
+// whole, to a fresh file. If rotation fails, p is appended to the current
+// file anyway and the rotation error is returned; rotation is retried on the
+// next write.
+func (lf *File) Write(p []byte) (int, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	var rotateErr error
+	if lf.maxSize > 0 && lf.size > 0 && lf.size+int64(len(p)) > lf.maxSize {
+		rotateErr = lf.rotate()
+	}
+	n, err := lf.f.Write(p)
+	lf.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// Close closes the current file.
+func (lf *File) Close() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	return lf.f.Close()
+}
+
+// rotate renames the current file to a backup and opens a fresh one. The
+// current file stays open until its replacement is, so a failed rotation
+// leaves a usable file behind. A missing file (removed by hand, or renamed
+// by an earlier rotation whose open failed) is not an error: a fresh file is
+// opened in its place.
+func (lf *File) rotate() error {
+	backup := lf.path + "." + lf.now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(lf.path, backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("logfile: rotate %s: %w", lf.path, err)
+	}
+	old := lf.f
+	if err := lf.open(); err != nil {
+		return err
+	}
+	// Everything written to the old file has already reached the OS.
+	_ = old.Close()
+	lf.prune()
+	return nil
+}
+
+// prune deletes backups beyond the configured count and age. Failures are
+// ignored: a leftover backup is better than a lost log line.
+func (lf *File) prune() {
+	if lf.maxBackups <= 0 && lf.maxAge <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(lf.path + ".*")
+	if err != nil {
+		return
+	}
+
+	prefix := filepath.Base(lf.path) + "."
+	var backups []string // newest first
+	for _, m := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimPrefix(filepath.Base(m), prefix)); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	slices.Sort(backups)
+	slices.Reverse(backups)
+
+	cutoff := lf.now().UTC().Add(-lf.maxAge)
+	for i, b := range backups {
+		stamp, _ := time.Parse(backupTimeFormat, strings.TrimPrefix(filepath.Base(b), prefix))
+		if (lf.maxBackups > 0 && i >= lf.maxBackups) || (lf.maxAge > 0 && stamp.Before(cutoff)) {
+			_ = os.Remove(b)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFile_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "proxy.log")
+
+	lf, err := Open(path, 1, 2, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = lf.Close() }()
+	clock := time.Unix(1_700_000_000, 0)
+	lf.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	line := []byte(strings.Repeat("x", 600*1024) + "\n")
+	for range 5 {
+		if _, err := lf.Write(line); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Errorf("backups = %v, want 2 (max_backups)", backups)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != int64(len(line)) {
+		t.Errorf("current file size = %d, want %d", info.Size(), len(line))
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %04o, want 0600", perm)
+	}
+}
+
+func TestFile_PrunesByAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "proxy.log")
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	old := path + "." + now.Add(-10*24*time.Hour).Format(backupTimeFormat)
+	recent := path + "." + now.Add(-time.Hour).Format(backupTimeFormat)
+	unrelated := path + ".keep"
+	for _, p := range []string{old, recent, unrelated} {
+		if err := os.WriteFile(p, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lf, err := Open(path, 1, 0, 7)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = lf.Close() }()
+	lf.now = func() time.Time { return now }
+
+	if _, err := lf.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := lf.Write(make([]byte, 1024*1024)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		path string
+		want bool
+	}{{old, false}, {recent, true}, {unrelated, true}} {
+		_, err := os.Stat(tt.path)
+		if exists := err == nil; exists != tt.want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(tt.path), exists, tt.want)
+		}
+	}
+}
+
+func TestFile_RotateFailureKeepsLogging(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "proxy.log")
+
+	lf, err := Open(path, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = lf.Close() }()
+	now := time.Unix(1_700_000_000, 0)
+	lf.now = func() time.Time { return now }
+
+	// A directory at the backup path makes the rename fail.
+	blocker := path + "." + now.UTC().Format(backupTimeFormat)
+	if err := os.Mkdir(blocker, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	big := make([]byte, 1024*1024)
+	if _, err := lf.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := lf.Write(big); err == nil {
+		t.Fatal("Write() with a failing rotation: expected error, got nil")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if want := int64(len("first\n") + len(big)); info.Size() != want {
+		t.Errorf("file size after failed rotation = %d, want %d", info.Size(), want)
+	}
+
+	// Once the obstruction is gone, the next write rotates normally.
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lf.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write() after clearing the obstruction: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "after\n" {
+		t.Errorf("current file = %q, want %q", data, "after\n")
+	}
+	if _, err := os.Stat(blocker); err != nil {
+		t.Errorf("backup missing after rotation: %v", err)
+	}
+}
@@ -2,53 +2,129 @@
 package metrics
 
 import (
+	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
 // Default histogram buckets for API latency.
 var defaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
 
+// Histogram buckets for request body sizes: 256 B to 64 MiB.
+var sizeBuckets = prometheus.ExponentialBuckets(256, 4, 10)
+
 // Metrics holds all Prometheus metric collectors for the proxy.
 type Metrics struct {
 	Registry *prometheus.Registry
 
+	pathPrefixes []string                       // path label values; see SetPathPrefixes
+	successRatio atomic.Pointer[func() float64] // see SetUpstreamSuccessRatio
+
 	RequestsTotal    *prometheus.CounterVec
+	RequestsByMethod *prometheus.CounterVec
 	RequestDuration  *prometheus.HistogramVec
+	RequestBytes     *prometheus.HistogramVec
 	RequestsInFlight prometheus.Gauge
+	ActiveClientIPs  prometheus.Gauge
+	ShedRequests     prometheus.Counter
+
+	RequestBodyErrors   *prometheus.CounterVec
+	ClientDisconnects   *prometheus.CounterVec
+	RequestsWithCookies prometheus.Counter
 
 	UpstreamDuration  *prometheus.HistogramVec
 	UpstreamResponses *prometheus.CounterVec
+	UpstreamTimeouts  *prometheus.CounterVec
+	UpstreamCanceled  *prometheus.CounterVec
+
+	UpstreamAuthFailures *prometheus.CounterVec
+	UpstreamIdleConns    prometheus.Gauge
+	UpstreamSuccessRatio prometheus.GaugeFunc
+
+	QueueDepth    prometheus.Gauge
+	QueueTimeouts prometheus.Counter
+	AdmissionWait *prometheus.HistogramVec
+
+	MaintenanceMode prometheus.Gauge
+
+	ShadowDuration *prometheus.HistogramVec
+	ShadowMismatch prometheus.Counter
+	ShadowFailures *prometheus.CounterVec
 }
 
-// New creates a Metrics instance with a custom registry and all collectors registered.
+// New creates a Metrics instance with a custom registry and all collectors
+// registered. It panics if registration fails; see NewWithError.
 func New() *Metrics {
-	reg := prometheus.NewRegistry()
+	m, err := NewWithError()
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
 
-	reg.MustRegister(collectors.NewGoCollector())
-	reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+// NewWithError is like New but returns registration failures, such as a
+// duplicate metric name, as an error instead of panicking.
+func NewWithError() (*Metrics, error) {
+	reg := prometheus.NewRegistry()
 
 	m := &Metrics{
-		Registry: reg,
+		Registry:     reg,
+		pathPrefixes: defaultPrefixes,
 
 		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "vulners_proxy_http_requests_total",
 			Help: "Total inbound HTTP requests.",
 		}, []string{"method", "status_code", "path_prefix"}),
 
+		RequestsByMethod: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_requests_by_method_total",
+			Help: "Total inbound HTTP requests by method only, for simple method-ratio queries.",
+		}, []string{"method"}),
+
 		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "vulners_proxy_http_request_duration_seconds",
 			Help:    "Inbound HTTP request latency in seconds.",
 			Buckets: defaultBuckets,
 		}, []string{"method", "status_code", "path_prefix"}),
 
+		RequestBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "vulners_proxy_request_bytes",
+			Help:    "Bytes read from inbound request bodies, by path prefix. Requests without a body are not observed.",
+			Buckets: sizeBuckets,
+		}, []string{"path_prefix"}),
+
 		RequestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "vulners_proxy_http_requests_in_flight",
 			Help: "Number of HTTP requests currently being processed.",
 		}),
 
+		ShedRequests: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vulners_proxy_shed_requests_total",
+			Help: "Total API requests rejected with 503 because server.max_in_flight was exceeded.",
+		}),
+
+		RequestBodyErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_request_body_errors_total",
+			Help: "Total failed reads of client request bodies while forwarding, by reason (client_disconnect or other).",
+		}, []string{"reason"}),
+
+		ClientDisconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_client_disconnects_total",
+			Help: "Total proxied requests whose client disconnected, by phase (before_response or during_response).",
+		}, []string{"phase"}),
+
+		RequestsWithCookies: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vulners_proxy_requests_with_cookies_total",
+			Help: "Total proxied requests that arrived with a Cookie header; cookies are never forwarded upstream.",
+		}),
+
 		UpstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "vulners_proxy_upstream_request_duration_seconds",
 			Help:    "Upstream call latency in seconds.",
@@ -57,19 +133,145 @@ func New() *Metrics {
 
 		UpstreamResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "vulners_proxy_upstream_responses_total",
-			Help: "Total upstream responses by method and status code.",
-		}, []string{"method", "status_code"}),
+			Help: "Total upstream responses by method, status code, and attempt (first or retry).",
+		}, []string{"method", "status_code", "attempt"}),
+
+		UpstreamTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_upstream_timeouts_total",
+			Help: "Total upstream requests that failed because the upstream was too slow.",
+		}, []string{"method"}),
+
+		UpstreamCanceled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_upstream_client_canceled_total",
+			Help: "Total upstream requests aborted because the client disconnected.",
+		}, []string{"method"}),
+
+		UpstreamAuthFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_upstream_auth_failures_total",
+			Help: "Total upstream responses rejecting the API key (401 or 403).",
+		}, []string{"status"}),
+
+		ActiveClientIPs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vulners_proxy_active_client_ips",
+			Help: "Approximate number of distinct client IPs seen over the last 5 minutes.",
+		}),
+
+		UpstreamIdleConns: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vulners_proxy_upstream_idle_conns",
+			Help: "Approximate number of idle upstream connections in the pool, tracked via httptrace.",
+		}),
+
+		MaintenanceMode: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vulners_proxy_maintenance_mode",
+			Help: "1 while maintenance mode is on and /api/* requests are rejected, else 0.",
+		}),
+
+		QueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "vulners_proxy_queue_depth",
+			Help: "Number of requests waiting for a free upstream slot.",
+		}),
+
+		QueueTimeouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vulners_proxy_queue_timeouts_total",
+			Help: "Total requests rejected after waiting too long for a free upstream slot.",
+		}),
+
+		AdmissionWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "vulners_proxy_admission_wait_seconds",
+			Help:    "Time spent waiting for a free upstream slot, by outcome.",
+			Buckets: defaultBuckets,
+		}, []string{"outcome"}),
+
+		ShadowDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "vulners_proxy_shadow_duration_seconds",
+			Help:    "Time to response headers for mirrored requests, by upstream (primary or shadow).",
+			Buckets: defaultBuckets,
+		}, []string{"upstream"}),
+
+		ShadowMismatch: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "vulners_proxy_shadow_mismatch_total",
+			Help: "Total mirrored requests where the shadow upstream's status code differed from the primary's.",
+		}),
+
+		ShadowFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "vulners_proxy_shadow_failures_total",
+			Help: "Total requests not compared with the shadow upstream, by reason (error or dropped).",
+		}, []string{"reason"}),
 	}
 
-	reg.MustRegister(
+	// The ratio is computed at scrape time, so it decays with the window
+	// even when no upstream calls are made.
+	m.UpstreamSuccessRatio = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "vulners_proxy_upstream_success_ratio",
+		Help: "Share of upstream calls over upstream.success_window answered with 2xx or 3xx; 1 with no calls in the window.",
+	}, func() float64 {
+		if ratio := m.successRatio.Load(); ratio != nil {
+			return (*ratio)()
+		}
+		return 1
+	})
+
+	for _, c := range []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		m.RequestsTotal,
+		m.RequestsByMethod,
 		m.RequestDuration,
+		m.RequestBytes,
 		m.RequestsInFlight,
+		m.ActiveClientIPs,
+		m.ShedRequests,
+		m.RequestBodyErrors,
+		m.ClientDisconnects,
+		m.RequestsWithCookies,
 		m.UpstreamDuration,
 		m.UpstreamResponses,
-	)
+		m.UpstreamTimeouts,
+		m.UpstreamCanceled,
+		m.UpstreamAuthFailures,
+		m.UpstreamIdleConns,
+		m.UpstreamSuccessRatio,
+		m.QueueDepth,
+		m.QueueTimeouts,
+		m.AdmissionWait,
+		m.MaintenanceMode,
+		m.ShadowDuration,
+		m.ShadowMismatch,
+		m.ShadowFailures,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("register metrics: %w", err)
+		}
+	}
 
-	return m
+	return m, nil
+}
+
+// Handler returns the Prometheus exposition handler for the registry. An
+// optional "prefix" query parameter limits the output to metric families
+// whose name starts with it, e.g. ?prefix=vulners_proxy_ to leave out the Go
+// runtime and process metrics.
+func (m *Metrics) Handler() http.Handler {
+	all := promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		if prefix == "" {
+			all.ServeHTTP(w, r)
+			return
+		}
+		promhttp.HandlerFor(prefixGatherer(m.Registry, prefix), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// prefixGatherer returns a Gatherer yielding the families of g whose name
+// starts with prefix.
+func prefixGatherer(g prometheus.Gatherer, prefix string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		return slices.DeleteFunc(families, func(f *dto.MetricFamily) bool {
+			return !strings.HasPrefix(f.GetName(), prefix)
+		}), err
+	})
 }
 
 // knownMethods lists the allowed HTTP method label values (bounded cardinality).
@@ -87,12 +289,84 @@ func NormalizeMethod(method string) string {
 	return "other"
 }
 
-// knownPrefixes lists the allowed path label values (bounded cardinality).
-var knownPrefixes = []string{"/api/v3", "/api/v4", "/healthz", "/proxy/status", "/metrics"}
+// Attempt label values. A logical request produces one "first" upstream call
+// and at most one "retry".
+const (
+	AttemptFirst = "first"
+	AttemptRetry = "retry"
+)
+
+// Shadow label values.
+const (
+	ShadowUpstreamPrimary = "primary"
+	ShadowUpstreamShadow  = "shadow"
+	ShadowFailureError    = "error"
+	ShadowFailureDropped  = "dropped"
+)
+
+// Request body error reason label values.
+const (
+	BodyErrorClientDisconnect = "client_disconnect"
+	BodyErrorOther            = "other"
+)
+
+// Client disconnect phase label values: before the response status was sent
+// (typically while waiting on upstream) or while streaming the body.
+const (
+	DisconnectBeforeResponse = "before_response"
+	DisconnectDuringResponse = "during_response"
+)
+
+// SetUpstreamSuccessRatio sets the function vulners_proxy_upstream_success_ratio
+// reports at scrape time. Until it is called the gauge reports 1.
+func (m *Metrics) SetUpstreamSuccessRatio(ratio func() float64) {
+	m.successRatio.Store(&ratio)
+}
+
+// defaultPrefixes lists the path label values used until SetPathPrefixes is called.
+var defaultPrefixes = []string{"/api/v3", "/api/v4", "/healthz", "/proxy/status", "/metrics"}
+
+// MaxPathPrefixes bounds how many distinct path_prefix label values can exist.
+const MaxPathPrefixes = 32
+
+// SetPathPrefixes replaces the path label values, typically with the prefixes
+// of the routes actually registered on the server. Empty and duplicate entries
+// are ignored and at most MaxPathPrefixes are kept; the number of prefixes
+// dropped because of the bound is returned. It must be called before the
+// server starts handling requests.
+func (m *Metrics) SetPathPrefixes(prefixes []string) int {
+	seen := make(map[string]bool, len(prefixes))
+	kept := make([]string, 0, len(prefixes))
+	dropped := 0
+	for _, p := range prefixes {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		if len(kept) == MaxPathPrefixes {
+			dropped++
+			continue
+		}
+		kept = append(kept, p)
+	}
+	m.pathPrefixes = kept
+	return dropped
+}
+
+// NormalizePath returns a bounded path label for Prometheus metrics using the
+// configured path prefixes.
+func (m *Metrics) NormalizePath(path string) string {
+	return normalizePath(path, m.pathPrefixes)
+}
 
-// NormalizePath returns a bounded path label for Prometheus metrics.
+// NormalizePath returns a bounded path label for Prometheus metrics using the
+// default path prefixes.
 func NormalizePath(path string) string {
-	for _, prefix := range knownPrefixes {
+	return normalizePath(path, defaultPrefixes)
+}
+
+func normalizePath(path string, prefixes []string) string {
+	for _, prefix := range prefixes {
 		if path == prefix || strings.HasPrefix(path, prefix+"/") || strings.HasPrefix(path, prefix+"?") {
 			return prefix
 		}
@@ -1,6 +1,11 @@
 package metrics
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -37,6 +42,54 @@ func TestNew_GathersMetrics(t *testing.T) {
 	}
 }
 
+func TestHandler_PrefixFilter(t *testing.T) {
+	m := New()
+	m.RequestsTotal.WithLabelValues("GET", "200", "/api/v3").Inc()
+
+	tests := []struct {
+		name      string
+		query     string
+		wantProxy bool
+		wantGo    bool
+	}{
+		{"no filter", "", true, true},
+		{"own metrics only", "?prefix=vulners_proxy_", true, false},
+		{"runtime only", "?prefix=go_", false, true},
+		{"no match", "?prefix=nothing_", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics"+tt.query, http.NoBody))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			body, _ := io.ReadAll(rec.Body)
+
+			if got := strings.Contains(string(body), "vulners_proxy_http_requests_total"); got != tt.wantProxy {
+				t.Errorf("vulners_proxy_http_requests_total present = %v, want %v", got, tt.wantProxy)
+			}
+			if got := strings.Contains(string(body), "go_goroutines"); got != tt.wantGo {
+				t.Errorf("go_goroutines present = %v, want %v", got, tt.wantGo)
+			}
+		})
+	}
+}
+
+func TestNewWithError(t *testing.T) {
+	// Each call owns its registry, so repeated construction never collides.
+	for range 2 {
+		m, err := NewWithError()
+		if err != nil {
+			t.Fatalf("NewWithError() error = %v", err)
+		}
+		if m.Registry == nil || m.RequestsTotal == nil {
+			t.Fatal("NewWithError() returned incomplete Metrics")
+		}
+	}
+}
+
 func TestNormalizeMethod(t *testing.T) {
 	tests := []struct {
 		method string
@@ -90,3 +143,43 @@ func TestNormalizePath(t *testing.T) {
 		})
 	}
 }
+
+func TestSetPathPrefixes(t *testing.T) {
+	m := New()
+	dropped := m.SetPathPrefixes([]string{"/api/v3", "/readyz", "/api/v3", ""})
+	if dropped != 0 {
+		t.Errorf("dropped = %d, want 0", dropped)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v3/search/lucene/", "/api/v3"},
+		{"/readyz", "/readyz"},
+		{"/api/v4/search/lucene/", "other"},
+		{"/healthz", "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := m.NormalizePath(tt.path); got != tt.want {
+				t.Errorf("NormalizePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPathPrefixes_Bounded(t *testing.T) {
+	m := New()
+	prefixes := make([]string, 0, MaxPathPrefixes+5)
+	for i := range MaxPathPrefixes + 5 {
+		prefixes = append(prefixes, fmt.Sprintf("/route%d", i))
+	}
+
+	if dropped := m.SetPathPrefixes(prefixes); dropped != 5 {
+		t.Errorf("dropped = %d, want 5", dropped)
+	}
+	if got := m.NormalizePath(fmt.Sprintf("/route%d", MaxPathPrefixes)); got != "other" {
+		t.Errorf("NormalizePath() for dropped prefix = %q, want %q", got, "other")
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+
+	"vulners-proxy-go/internal/model"
+)
+
+// BodyLimitRule overrides the default request body limit for requests whose
+// path equals or is nested under PathPrefix and, if Methods is non-empty,
+// whose method is listed. MaxBytes of 0 removes the limit.
+type BodyLimitRule struct {
+	Methods    []string
+	PathPrefix string
+	MaxBytes   int64
+}
+
+type bodyLimitRoute struct {
+	rule  BodyLimitRule
+	limit echo.MiddlewareFunc // nil when the rule removes the limit
+}
+
+// BodyLimit returns a middleware enforcing defaultMax bytes per request body,
+// or the limit of the matching rule with the longest PathPrefix. Oversized
+// bodies are rejected with 413 by Echo's body limit middleware, including
+// chunked bodies, which are counted as they are read.
+func BodyLimit(defaultMax int64, rules []BodyLimitRule) echo.MiddlewareFunc {
+	routes := make([]bodyLimitRoute, 0, len(rules))
+	for _, r := range rules {
+		route := bodyLimitRoute{rule: r}
+		if r.MaxBytes > 0 {
+			route.limit = echomw.BodyLimit(fmt.Sprintf("%dB", r.MaxBytes))
+		}
+		routes = append(routes, route)
+	}
+	slices.SortStableFunc(routes, func(a, b bodyLimitRoute) int {
+		return len(b.rule.PathPrefix) - len(a.rule.PathPrefix)
+	})
+	defaultLimit := echomw.BodyLimit(fmt.Sprintf("%dB", defaultMax))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		limited := defaultLimit(next)
+		perRoute := make([]echo.HandlerFunc, len(routes))
+		for i, r := range routes {
+			perRoute[i] = next
+			if r.limit != nil {
+				perRoute[i] = r.limit(next)
+			}
+		}
+
+		return func(c echo.Context) error {
+			req := c.Request()
+			for i, r := range routes {
+				if !model.PathUnder(req.URL.Path, r.rule.PathPrefix) {
+					continue
+				}
+				if len(r.rule.Methods) > 0 && !slices.ContainsFunc(r.rule.Methods, func(m string) bool {
+					return strings.EqualFold(m, req.Method)
+				}) {
+					continue
+				}
+				return perRoute[i](c)
+			}
+			return limited(c)
+		}
+	}
+}
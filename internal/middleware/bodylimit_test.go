@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestBodyLimit(t *testing.T) {
+	rules := []BodyLimitRule{
+		{Methods: []string{"post"}, PathPrefix: "/api/v4", MaxBytes: 20},
+		{Methods: []string{"POST"}, PathPrefix: "/api/v4/audit", MaxBytes: 0},
+		{PathPrefix: "/upload", MaxBytes: 30},
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		size     int
+		wantCode int
+	}{
+		{"default within limit", http.MethodPost, "/api/v3/search", 10, http.StatusOK},
+		{"default over limit", http.MethodPost, "/api/v3/search", 11, http.StatusRequestEntityTooLarge},
+		{"override raises limit", http.MethodPost, "/api/v4/search", 20, http.StatusOK},
+		{"override over limit", http.MethodPost, "/api/v4/search", 21, http.StatusRequestEntityTooLarge},
+		{"method mismatch uses default", http.MethodPut, "/api/v4/search", 11, http.StatusRequestEntityTooLarge},
+		{"longest prefix exempts", http.MethodPost, "/api/v4/audit/linux", 1000, http.StatusOK},
+		{"exact prefix path", http.MethodPost, "/api/v4/audit", 1000, http.StatusOK},
+		{"sibling path not matched", http.MethodPost, "/api/v4/auditing", 21, http.StatusRequestEntityTooLarge},
+		{"sibling path uses parent rule", http.MethodPost, "/api/v4/auditing", 20, http.StatusOK},
+		{"prefix without separator not matched", http.MethodPut, "/uploads", 11, http.StatusRequestEntityTooLarge},
+		{"any method", http.MethodPut, "/upload/x", 30, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(BodyLimit(10, rules))
+			e.Any("/*", func(c echo.Context) error {
+				if _, err := io.ReadAll(c.Request().Body); err != nil {
+					return err
+				}
+				return c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestBodyLimit_ChunkedBody(t *testing.T) {
+	e := echo.New()
+	e.Use(BodyLimit(10, nil))
+	e.POST("/upload", func(c echo.Context) error {
+		if _, err := io.ReadAll(c.Request().Body); err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 11)))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+}
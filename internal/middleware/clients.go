@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"cmp"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxTrackedIPs bounds the distinct IPs kept per bucket. Past it, new IPs are
+// not recorded, so the distinct count is a lower bound under a flood.
+const maxTrackedIPs = 100_000
+
+// ClientTracker counts requests per client IP in fixed time buckets covering a
+// sliding window. It backs an aggregate "active clients" gauge and a periodic
+// top-N log, so no per-IP metric labels are needed.
+type ClientTracker struct {
+	mu      sync.Mutex
+	bucket  time.Duration
+	buckets []clientBucket // ring; index is bucket start / bucket width mod len
+
+	now func() time.Time
+}
+
+type clientBucket struct {
+	start  time.Time
+	counts map[string]int
+}
+
+// ClientCount is a client IP and its request count over the window.
+type ClientCount struct {
+	IP       string `json:"ip"`
+	Requests int    `json:"requests"`
+}
+
+// NewClientTracker returns a ClientTracker covering window, split into
+// buckets of the given width.
+func NewClientTracker(window, bucket time.Duration) *ClientTracker {
+	n := max(int(window/bucket), 1)
+	return &ClientTracker{
+		bucket:  bucket,
+		buckets: make([]clientBucket, n),
+		now:     time.Now,
+	}
+}
+
+// PeerIP returns the direct TCP peer address of r without the port. Forwarded
+// headers are ignored so clients cannot spoof their identity.
+func PeerIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr may lack a port (e.g. Unix socket); use it as-is.
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// Middleware returns an Echo middleware that records each request's peer IP.
+func (t *ClientTracker) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			t.Record(PeerIP(c.Request()))
+			return next(c)
+		}
+	}
+}
+
+// Record counts one request from ip in the current bucket.
+func (t *ClientTracker) Record(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := t.now().Truncate(t.bucket)
+	b := &t.buckets[int(start.UnixNano()/int64(t.bucket))%len(t.buckets)]
+	if !b.start.Equal(start) {
+		b.start = start
+		b.counts = make(map[string]int)
+	}
+	if _, ok := b.counts[ip]; ok || len(b.counts) < maxTrackedIPs {
+		b.counts[ip]++
+	}
+}
+
+// Snapshot returns the number of distinct IPs seen within the window and the
+// n heaviest of them, busiest first.
+func (t *ClientTracker) Snapshot(n int) (distinct int, top []ClientCount) {
+	t.mu.Lock()
+	totals := make(map[string]int)
+	oldest := t.now().Truncate(t.bucket).Add(-time.Duration(len(t.buckets)-1) * t.bucket)
+	for _, b := range t.buckets {
+		if b.start.Before(oldest) {
+			continue
+		}
+		for ip, c := range b.counts {
+			totals[ip] += c
+		}
+	}
+	t.mu.Unlock()
+
+	top = make([]ClientCount, 0, len(totals))
+	for ip, c := range totals {
+		top = append(top, ClientCount{IP: ip, Requests: c})
+	}
+	slices.SortFunc(top, func(a, b ClientCount) int {
+		if c := cmp.Compare(b.Requests, a.Requests); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.IP, b.IP)
+	})
+	return len(totals), top[:min(n, len(top))]
+}
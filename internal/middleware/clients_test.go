@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestClientTracker_Snapshot(t *testing.T) {
+	clock := time.Unix(1_700_000_000, 0)
+	tr := NewClientTracker(3*time.Minute, time.Minute)
+	tr.now = func() time.Time { return clock }
+
+	for range 3 {
+		tr.Record("10.0.0.1")
+	}
+	tr.Record("10.0.0.2")
+
+	clock = clock.Add(time.Minute)
+	tr.Record("10.0.0.2")
+	tr.Record("10.0.0.3")
+
+	distinct, top := tr.Snapshot(2)
+	if distinct != 3 {
+		t.Errorf("distinct = %d, want 3", distinct)
+	}
+	want := []ClientCount{{"10.0.0.1", 3}, {"10.0.0.2", 2}}
+	if len(top) != len(want) || top[0] != want[0] || top[1] != want[1] {
+		t.Errorf("top = %v, want %v", top, want)
+	}
+
+	// Once the first bucket leaves the window only its IPs drop out.
+	clock = clock.Add(2 * time.Minute)
+	if distinct, _ := tr.Snapshot(10); distinct != 2 {
+		t.Errorf("distinct after expiry = %d, want 2", distinct)
+	}
+
+	clock = clock.Add(time.Hour)
+	if distinct, top := tr.Snapshot(10); distinct != 0 || len(top) != 0 {
+		t.Errorf("Snapshot after idle = (%d, %v), want empty", distinct, top)
+	}
+}
+
+func TestClientTracker_MiddlewareUsesPeerIP(t *testing.T) {
+	tr := NewClientTracker(time.Minute, time.Minute)
+	e := echo.New()
+	e.Use(tr.Middleware())
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	_, top := tr.Snapshot(1)
+	if len(top) != 1 || top[0].IP != "192.0.2.7" {
+		t.Errorf("top = %v, want peer IP 192.0.2.7", top)
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxTrackedInFlight bounds the requests an InFlightRegistry holds. Requests
+// beyond it are served as usual but only counted, so a flood cannot grow the
+// registry without limit.
+const maxTrackedInFlight = 10_000
+
+// InFlightRegistry records the requests currently being processed, for a
+// snapshot when requests pile up during an incident.
+type InFlightRegistry struct {
+	mu        sync.Mutex
+	nextID    uint64
+	entries   map[uint64]InFlightRequest
+	untracked int // in-flight requests not recorded because the registry was full
+	max       int
+
+	now func() time.Time
+}
+
+// InFlightRequest describes one request in flight. The path never includes
+// the query string, which may carry API keys.
+type InFlightRequest struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ClientIP  string    `json:"client_ip"`
+	RequestID string    `json:"request_id,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	AgeMs     int64     `json:"age_ms"`
+}
+
+// NewInFlightRegistry returns an empty InFlightRegistry.
+func NewInFlightRegistry() *InFlightRegistry {
+	return &InFlightRegistry{
+		entries: make(map[uint64]InFlightRequest),
+		max:     maxTrackedInFlight,
+		now:     time.Now,
+	}
+}
+
+// Middleware returns an Echo middleware that registers each request for as
+// long as it is being processed, including streaming its response. It must
+// be installed after the RequestID middleware for request IDs to be recorded.
+func (r *InFlightRegistry) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id, ok := r.add(InFlightRequest{
+				Method:    req.Method,
+				Path:      req.URL.Path,
+				ClientIP:  PeerIP(req),
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			})
+			defer r.remove(id, ok)
+			return next(c)
+		}
+	}
+}
+
+// add records e, stamped with the current time, and returns its key. ok is
+// false when the registry was full and e was only counted.
+func (r *InFlightRegistry) add(e InFlightRequest) (id uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) >= r.max {
+		r.untracked++
+		return 0, false
+	}
+	r.nextID++
+	e.StartedAt = r.now()
+	r.entries[r.nextID] = e
+	return r.nextID, true
+}
+
+func (r *InFlightRegistry) remove(id uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !ok {
+		r.untracked--
+		return
+	}
+	delete(r.entries, id)
+}
+
+// Snapshot returns the recorded requests, oldest first, with their current
+// age, and the number of further in-flight requests that were not recorded
+// because the registry was full.
+func (r *InFlightRegistry) Snapshot() (requests []InFlightRequest, untracked int) {
+	r.mu.Lock()
+	now := r.now()
+	requests = make([]InFlightRequest, 0, len(r.entries))
+	for _, e := range r.entries {
+		e.AgeMs = now.Sub(e.StartedAt).Milliseconds()
+		requests = append(requests, e)
+	}
+	untracked = r.untracked
+	r.mu.Unlock()
+
+	slices.SortFunc(requests, func(a, b InFlightRequest) int {
+		return cmp.Or(a.StartedAt.Compare(b.StartedAt), cmp.Compare(a.Path, b.Path))
+	})
+	return requests, untracked
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+func TestInFlightRegistry(t *testing.T) {
+	r := NewInFlightRegistry()
+	r.max = 1
+	entered := make(chan struct{}, 2)
+	release := make(chan struct{})
+
+	e := echo.New()
+	e.Use(echomw.RequestID())
+	e.Use(r.Middleware())
+	e.GET("/api/v3/*", func(c echo.Context) error {
+		entered <- struct{}{}
+		<-release
+		return c.NoContent(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	serve := func(target string) {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.RemoteAddr = "192.0.2.1:1234"
+		e.ServeHTTP(httptest.NewRecorder(), req)
+		done <- struct{}{}
+	}
+	go serve("/api/v3/search/lucene/?query=x&apiKey=secret")
+	<-entered
+	time.Sleep(time.Millisecond) // give the second request a later start time
+	go serve("/api/v3/archive/collection/")
+	<-entered
+
+	requests, untracked := r.Snapshot()
+	if untracked != 1 {
+		t.Errorf("untracked = %d, want 1", untracked)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("len(requests) = %d, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Method != http.MethodGet || got.Path != "/api/v3/search/lucene/" || got.ClientIP != "192.0.2.1" {
+		t.Errorf("request = %+v, want GET /api/v3/search/lucene/ from 192.0.2.1 without query", got)
+	}
+	if got.RequestID == "" {
+		t.Error("RequestID is empty")
+	}
+	if got.AgeMs < 0 || got.StartedAt.IsZero() {
+		t.Errorf("StartedAt = %v, AgeMs = %d", got.StartedAt, got.AgeMs)
+	}
+
+	close(release)
+	<-done
+	<-done
+	if requests, untracked := r.Snapshot(); len(requests) != 0 || untracked != 0 {
+		t.Errorf("after completion: %d requests, %d untracked; want none", len(requests), untracked)
+	}
+}
+
+func TestInFlightRegistry_SnapshotOrder(t *testing.T) {
+	r := NewInFlightRegistry()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := base
+	r.now = func() time.Time { return now }
+
+	r.add(InFlightRequest{Path: "/b"})
+	now = base.Add(time.Second)
+	r.add(InFlightRequest{Path: "/c"})
+	now = base.Add(-time.Second)
+	r.add(InFlightRequest{Path: "/a"})
+	now = base.Add(5 * time.Second)
+
+	requests, _ := r.Snapshot()
+	want := []struct {
+		path  string
+		ageMs int64
+	}{{"/a", 6000}, {"/b", 5000}, {"/c", 4000}}
+	if len(requests) != len(want) {
+		t.Fatalf("len(requests) = %d, want %d", len(requests), len(want))
+	}
+	for i, w := range want {
+		if requests[i].Path != w.path || requests[i].AgeMs != w.ageMs {
+			t.Errorf("requests[%d] = %s age %dms, want %s age %dms", i, requests[i].Path, requests[i].AgeMs, w.path, w.ageMs)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/metrics"
+)
+
+// LoadShed returns an Echo middleware that answers requests under prefixes
+// with 503 and Retry-After while more than maxInFlight requests are being
+// processed, so that overload fails fast instead of slowing every request
+// down. It counts every request passing through it, the same quantity as
+// the vulners_proxy_http_requests_in_flight gauge, but only sheds those
+// under prefixes; health and metrics endpoints are never rejected. The
+// metrics parameter may be nil.
+func LoadShed(maxInFlight int, retryAfter time.Duration, prefixes []string, m *metrics.Metrics) echo.MiddlewareFunc {
+	// Retry-After takes whole seconds; round up so a sub-second value does
+	// not become "0".
+	retry := strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds()))))
+	shed := PathPrefixSkipper(prefixes)
+	var inFlight atomic.Int64
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			if n > int64(maxInFlight) && shed(c) {
+				if m != nil {
+					m.ShedRequests.Inc()
+				}
+				c.Response().Header().Set("Retry-After", retry)
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "server is overloaded; retry later")
+			}
+			return next(c)
+		}
+	}
+}
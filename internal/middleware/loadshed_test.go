@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"vulners-proxy-go/internal/metrics"
+)
+
+func TestLoadShed(t *testing.T) {
+	m := metrics.New()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	e := echo.New()
+	e.Use(LoadShed(1, 1500*time.Millisecond, []string{"/api/v3"}, m))
+	e.GET("/api/v3/slow", func(c echo.Context) error {
+		close(entered)
+		<-release
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/api/v3/fast", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+	e.GET("/healthz", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		return rec
+	}
+
+	done := make(chan int)
+	go func() { done <- serve("/api/v3/slow").Code }()
+	<-entered
+
+	tests := []struct {
+		name      string
+		path      string
+		wantCode  int
+		wantRetry string
+	}{
+		{"API request shed", "/api/v3/fast", http.StatusServiceUnavailable, "2"},
+		{"health endpoint exempt", "/healthz", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.path)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetry {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetry)
+			}
+		})
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want %d", code, http.StatusOK)
+	}
+	if rec := serve("/api/v3/fast"); rec.Code != http.StatusOK {
+		t.Errorf("status after load drops = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == "vulners_proxy_shed_requests_total" {
+			if got := f.GetMetric()[0].GetCounter().GetValue(); got != 1 {
+				t.Errorf("shed_requests_total = %v, want 1", got)
+			}
+			return
+		}
+	}
+	t.Error("vulners_proxy_shed_requests_total not gathered")
+}
@@ -3,6 +3,7 @@ package middleware
 
 import (
 	"log/slog"
+	"math/rand/v2"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -15,29 +16,54 @@ var healthPaths = map[string]bool{
 	"/proxy/status": true,
 }
 
+// LogSampling thins out access log lines for successful requests.
+type LogSampling struct {
+	// Rate is the share (0.0–1.0) of successful requests logged at Info;
+	// the others are logged at Debug. Errors are always logged at Info.
+	Rate float64
+	// SlowThreshold keeps requests taking longer than it at Info regardless
+	// of Rate. 0 disables the exemption.
+	SlowThreshold time.Duration
+}
+
 // RequestLogger returns an Echo middleware that logs each request with slog.
-// Health-check paths are logged at Debug level; all other paths at Info.
-func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
+// Health-check paths are logged at Debug level; all other paths at Info,
+// except successful requests not picked by sampling, which go to Debug.
+// Requests that returned an error or a status of 400 or above are never
+// sampled out.
+//
+// With useRouteTemplate, "path" is the matched route (e.g. "/api/v3/*")
+// rather than the request path, keeping log cardinality low; the request
+// path is added as "raw_path" when Debug logging is enabled.
+func RequestLogger(logger *slog.Logger, useRouteTemplate bool, sampling LogSampling) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			start := time.Now()
 
 			err := next(c)
+			elapsed := time.Since(start)
 
 			req := c.Request()
 			res := c.Response()
 
+			path := req.URL.Path
+			if useRouteTemplate {
+				path = c.Path()
+			}
 			attrs := []any{
 				"method", req.Method,
-				"path", req.URL.Path,
+				"path", path,
 				"status", res.Status,
-				"duration_ms", time.Since(start).Milliseconds(),
+				"duration_ms", elapsed.Milliseconds(),
 				"request_id", res.Header().Get(echo.HeaderXRequestID),
 				"remote_ip", c.RealIP(),
 				"bytes_out", res.Size,
 			}
+			if useRouteTemplate && logger.Enabled(req.Context(), slog.LevelDebug) {
+				attrs = append(attrs, "raw_path", req.URL.Path)
+			}
 
-			if healthPaths[req.URL.Path] {
+			if healthPaths[req.URL.Path] || !sampling.keep(err, res.Status, elapsed) {
 				logger.Debug("request", attrs...)
 			} else {
 				logger.Info("request", attrs...)
@@ -47,3 +73,14 @@ func RequestLogger(logger *slog.Logger) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// keep reports whether a request's log line is written at Info.
+func (s LogSampling) keep(err error, status int, elapsed time.Duration) bool {
+	if s.Rate >= 1 || err != nil || status >= 400 {
+		return true
+	}
+	if s.SlowThreshold > 0 && elapsed > s.SlowThreshold {
+		return true
+	}
+	return rand.Float64() < s.Rate
+}
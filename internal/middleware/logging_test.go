@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -15,7 +16,7 @@ func TestRequestLogger(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	e := echo.New()
-	e.Use(RequestLogger(logger))
+	e.Use(RequestLogger(logger, false, LogSampling{Rate: 1}))
 	e.GET("/test", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -37,7 +38,7 @@ func TestRequestLogger_HealthzAtDebugLevel(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	e := echo.New()
-	e.Use(RequestLogger(logger))
+	e.Use(RequestLogger(logger, false, LogSampling{Rate: 1}))
 	e.GET("/healthz", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -59,7 +60,7 @@ func TestRequestLogger_HealthzVisibleAtDebugLevel(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	e := echo.New()
-	e.Use(RequestLogger(logger))
+	e.Use(RequestLogger(logger, false, LogSampling{Rate: 1}))
 	e.GET("/healthz", func(c echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -72,3 +73,87 @@ func TestRequestLogger_HealthzVisibleAtDebugLevel(t *testing.T) {
 		t.Errorf("expected log output at Debug level for /healthz, got %q", buf.String())
 	}
 }
+
+func TestRequestLogger_RouteTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       slog.Level
+		wantRawPath bool
+	}{
+		{"info omits raw path", slog.LevelInfo, false},
+		{"debug adds raw path", slog.LevelDebug, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level}))
+			e := echo.New()
+			e.Use(RequestLogger(logger, true, LogSampling{Rate: 1}))
+			e.GET("/api/v3/*", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/search/id/?id=CVE-2024-1234", http.NoBody)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			out := buf.String()
+			if !strings.Contains(out, "path=/api/v3/*") {
+				t.Errorf("log = %q, want path=/api/v3/*", out)
+			}
+			if got := strings.Contains(out, "raw_path=/api/v3/search/id/"); got != tt.wantRawPath {
+				t.Errorf("raw_path logged = %v, want %v; log = %q", got, tt.wantRawPath, out)
+			}
+		})
+	}
+}
+
+func TestRequestLogger_Sampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		sampling LogSampling
+		target   string
+		wantInfo bool
+	}{
+		{"rate 1 logs success", LogSampling{Rate: 1}, "/ok", true},
+		{"rate 0 drops success", LogSampling{Rate: 0}, "/ok", false},
+		{"rate 0 keeps server error", LogSampling{Rate: 0}, "/fail", true},
+		{"rate 0 keeps returned error", LogSampling{Rate: 0}, "/error", true},
+		{"rate 0 keeps unknown route", LogSampling{Rate: 0}, "/missing", true},
+		{"rate 0 keeps slow request", LogSampling{Rate: 0, SlowThreshold: time.Millisecond}, "/slow", true},
+		{"rate 0 drops fast request under threshold", LogSampling{Rate: 0, SlowThreshold: time.Hour}, "/slow", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			e := echo.New()
+			e.Use(RequestLogger(logger, false, tt.sampling))
+			e.GET("/ok", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+			e.GET("/fail", func(c echo.Context) error {
+				return c.String(http.StatusBadGateway, "fail")
+			})
+			e.GET("/error", func(_ echo.Context) error {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "busy")
+			})
+			e.GET("/slow", func(c echo.Context) error {
+				time.Sleep(5 * time.Millisecond)
+				return c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			out := buf.String()
+			if !strings.Contains(out, "msg=request") {
+				t.Fatalf("request not logged at all: %q", out)
+			}
+			if got := strings.Contains(out, "level=INFO"); got != tt.wantInfo {
+				t.Errorf("logged at info = %v, want %v; output %q", got, tt.wantInfo, out)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"errors"
+	"io"
+	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -11,7 +14,9 @@ import (
 )
 
 // MetricsMiddleware returns an Echo middleware that records Prometheus metrics
-// for each inbound request.
+// for each inbound request. The size of a request body is the number of bytes
+// actually read from it, so a body cut short by an upstream failure or a
+// body limit counts only the part that was consumed.
 func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -20,6 +25,12 @@ func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
 
 			start := time.Now()
 
+			var body *CountingBody
+			if req := c.Request(); req.Body != nil && req.Body != http.NoBody {
+				body = &CountingBody{ReadCloser: req.Body}
+				req.Body = body
+			}
+
 			err := next(c)
 
 			// Resolve the actual status code. When a handler returns an
@@ -36,13 +47,36 @@ func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
 
 			status := strconv.Itoa(statusCode)
 			method := metrics.NormalizeMethod(c.Request().Method)
-			path := metrics.NormalizePath(c.Request().URL.Path)
+			path := m.NormalizePath(c.Request().URL.Path)
 			duration := time.Since(start).Seconds()
 
 			m.RequestsTotal.WithLabelValues(method, status, path).Inc()
+			m.RequestsByMethod.WithLabelValues(method).Inc()
 			m.RequestDuration.WithLabelValues(method, status, path).Observe(duration)
+			if body != nil {
+				m.RequestBytes.WithLabelValues(path).Observe(float64(body.Count()))
+			}
 
 			return err
 		}
 	}
 }
+
+// CountingBody counts the bytes read from a request or response body. The
+// count may be read while another goroutine is still reading, such as the
+// upstream transport after the handler has returned.
+type CountingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *CountingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes read so far.
+func (b *CountingBody) Count() int64 {
+	return b.n.Load()
+}
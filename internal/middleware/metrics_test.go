@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -199,3 +201,114 @@ func TestMetricsMiddleware_RouterNotFound(t *testing.T) {
 	}
 	t.Error("expected vulners_proxy_http_requests_total with path_prefix=other, method=GET, status_code=404")
 }
+
+func TestMetricsMiddleware_RequestsByMethod(t *testing.T) {
+	m := metrics.New()
+
+	e := echo.New()
+	e.Use(MetricsMiddleware(m))
+	e.Any("/api/v3/*", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v3/search"},
+		{http.MethodGet, "/api/v3/missing"},
+		{http.MethodPost, "/api/v3/audit"},
+		{"PROPFIND", "/api/v3/search"},
+	} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, http.NoBody))
+	}
+
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "vulners_proxy_requests_by_method_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			if len(metric.GetLabel()) != 1 {
+				t.Fatalf("labels = %v, want only method", metric.GetLabel())
+			}
+			got[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+		}
+	}
+	want := map[string]float64{"GET": 2, "POST": 1, "other": 1}
+	if len(got) != len(want) {
+		t.Errorf("requests_by_method = %v, want %v", got, want)
+	}
+	for method, n := range want {
+		if got[method] != n {
+			t.Errorf("requests_by_method{method=%q} = %v, want %v", method, got[method], n)
+		}
+	}
+}
+
+func TestMetricsMiddleware_RequestBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		read      int64 // bytes the handler reads; -1 reads all
+		wantCount uint64
+		wantSum   float64
+	}{
+		{"full body", http.MethodPost, strings.Repeat("a", 1000), -1, 1, 1000},
+		{"partially read body", http.MethodPost, strings.Repeat("a", 1000), 300, 1, 300},
+		{"no body", http.MethodGet, "", -1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.New()
+
+			e := echo.New()
+			e.Use(MetricsMiddleware(m))
+			e.Any("/api/v4/audit/*", func(c echo.Context) error {
+				body := io.Reader(c.Request().Body)
+				if tt.read >= 0 {
+					body = io.LimitReader(body, tt.read)
+				}
+				_, _ = io.Copy(io.Discard, body)
+				if tt.read >= 0 {
+					// Simulate the upstream call failing mid-body.
+					return echo.NewHTTPError(http.StatusBadGateway, "upstream failed")
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			var body io.Reader = http.NoBody
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "/api/v4/audit/host/", body)
+			e.ServeHTTP(httptest.NewRecorder(), req)
+
+			families, err := m.Registry.Gather()
+			if err != nil {
+				t.Fatalf("Gather() error = %v", err)
+			}
+			var count uint64
+			var sum float64
+			for _, f := range families {
+				if f.GetName() != "vulners_proxy_request_bytes" {
+					continue
+				}
+				for _, metric := range f.GetMetric() {
+					if lp := metric.GetLabel(); len(lp) != 1 || lp[0].GetValue() != "/api/v4" {
+						t.Errorf("labels = %v, want path_prefix=/api/v4", lp)
+					}
+					count += metric.GetHistogram().GetSampleCount()
+					sum += metric.GetHistogram().GetSampleSum()
+				}
+			}
+			if count != tt.wantCount || sum != tt.wantSum {
+				t.Errorf("observed %d samples summing to %v, want %d summing to %v", count, sum, tt.wantCount, tt.wantSum)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MaxPathLength returns an Echo middleware that answers requests whose
+// escaped URL path is longer than maxBytes with 414. The query string is not
+// counted. Register it with Echo.Pre so oversized paths are rejected before
+// routing.
+func MaxPathLength(maxBytes int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if len(c.Request().URL.EscapedPath()) > maxBytes {
+				return echo.NewHTTPError(http.StatusRequestURITooLong, "request path too long")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMaxPathLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		target   string
+		wantCode int
+	}{
+		{"within limit", 32, "/api/v3/search/", http.StatusOK},
+		{"at limit", 16, "/api/v3/" + strings.Repeat("a", 8), http.StatusOK},
+		{"over limit", 16, "/api/v3/" + strings.Repeat("a", 9), http.StatusRequestURITooLong},
+		{"query not counted", 16, "/api/v3/search/?query=" + strings.Repeat("a", 100), http.StatusOK},
+		{"escaped length counted", 16, "/api/v3/" + strings.Repeat("%20", 3), http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Pre(MaxPathLength(tt.max))
+			called := false
+			e.GET("/api/v3/*", func(c echo.Context) error {
+				called = true
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == http.StatusOK)
+			}
+		})
+	}
+}
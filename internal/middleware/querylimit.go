@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// MaxQueryParams returns an Echo middleware that answers requests carrying
+// more than maxParams query parameters with 400. Every non-empty
+// "&"-separated pair counts, so a repeated name counts once per occurrence.
+// Register it with Echo.Pre so the check runs before routing.
+func MaxQueryParams(maxParams int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if countQueryParams(c.Request().URL.RawQuery) > maxParams {
+				return echo.NewHTTPError(http.StatusBadRequest,
+					"too many query parameters; at most "+strconv.Itoa(maxParams)+" are allowed")
+			}
+			return next(c)
+		}
+	}
+}
+
+// countQueryParams counts the non-empty pairs in a raw query string without
+// decoding it.
+func countQueryParams(rawQuery string) int {
+	n := 0
+	for pair := range strings.SplitSeq(rawQuery, "&") {
+		if pair != "" {
+			n++
+		}
+	}
+	return n
+}
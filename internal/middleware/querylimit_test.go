@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMaxQueryParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		query    string
+		wantCode int
+	}{
+		{"no query", 2, "", http.StatusOK},
+		{"at limit", 2, "query=a&skip=0", http.StatusOK},
+		{"over limit", 2, "query=a&skip=0&size=10", http.StatusBadRequest},
+		{"repeated name counts each time", 2, "a=1&a=2&a=3", http.StatusBadRequest},
+		{"empty pairs ignored", 2, "a=1&&b=2&", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Pre(MaxQueryParams(tt.max))
+			called := false
+			e.GET("/api/v3/*", func(c echo.Context) error {
+				called = true
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v3/search/lucene/?"+tt.query, http.NoBody)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if called != (tt.wantCode == http.StatusOK) {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCode == http.StatusOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+
+	"vulners-proxy-go/internal/model"
+)
+
+// slidingWindowExpiry is how long an idle identifier is kept before its
+// counters are discarded.
+const slidingWindowExpiry = 3 * time.Minute
+
+// SlidingWindowStore is an echo RateLimiterStore that allows up to limit
+// requests per window (one second or one minute) per identifier. Unlike a token bucket it
+// has no burst allowance: the count from the previous window is weighted by
+// how much of it still overlaps the sliding window, so traffic cannot double
+// up across a window boundary.
+type SlidingWindowStore struct {
+	mu          sync.Mutex
+	limit       float64
+	window      time.Duration
+	counters    map[string]*windowCounter
+	lastCleanup time.Time
+
+	now func() time.Time
+}
+
+type windowCounter struct {
+	start    time.Time // start of the current window
+	current  float64   // requests allowed in the current window
+	previous float64   // requests allowed in the window before it
+}
+
+// NewSlidingWindowStore returns a SlidingWindowStore allowing requestsPerSecond
+// requests per identifier.
+func NewSlidingWindowStore(requestsPerSecond float64) *SlidingWindowStore {
+	return newSlidingWindowStore(requestsPerSecond, time.Second)
+}
+
+// NewMinuteWindowStore returns a SlidingWindowStore allowing requestsPerMinute
+// requests per identifier over a sliding one-minute window.
+func NewMinuteWindowStore(requestsPerMinute float64) *SlidingWindowStore {
+	return newSlidingWindowStore(requestsPerMinute, time.Minute)
+}
+
+func newSlidingWindowStore(limit float64, window time.Duration) *SlidingWindowStore {
+	return &SlidingWindowStore{
+		limit:    limit,
+		window:   window,
+		counters: make(map[string]*windowCounter),
+		now:      time.Now,
+	}
+}
+
+// Allow reports whether a request from identifier is within the limit and,
+// if so, counts it.
+func (s *SlidingWindowStore) Allow(identifier string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastCleanup) > slidingWindowExpiry {
+		s.cleanup(now)
+	}
+
+	wc, ok := s.counters[identifier]
+	if !ok {
+		wc = &windowCounter{start: now}
+		s.counters[identifier] = wc
+	}
+
+	// Roll the window forward. After a gap of two or more windows nothing
+	// from the past still overlaps.
+	if elapsed := now.Sub(wc.start); elapsed >= s.window {
+		windows := elapsed / s.window
+		if windows == 1 {
+			wc.previous = wc.current
+		} else {
+			wc.previous = 0
+		}
+		wc.current = 0
+		wc.start = wc.start.Add(windows * s.window)
+	}
+
+	overlap := 1 - float64(now.Sub(wc.start))/float64(s.window)
+	if wc.previous*overlap+wc.current >= s.limit {
+		return false, nil
+	}
+	wc.current++
+	return true, nil
+}
+
+func (s *SlidingWindowStore) cleanup(now time.Time) {
+	for id, wc := range s.counters {
+		if now.Sub(wc.start) > slidingWindowExpiry {
+			delete(s.counters, id)
+		}
+	}
+	s.lastCleanup = now
+}
+
+// AdjustableStore is an echo RateLimiterStore whose requests-per-second
+// limit can be changed at runtime. Changing it replaces the underlying store
+// built by newStore, so per-client state starts afresh.
+type AdjustableStore struct {
+	mu       sync.RWMutex
+	rps      float64
+	store    echomw.RateLimiterStore
+	newStore func(requestsPerSecond float64) echomw.RateLimiterStore
+}
+
+// NewAdjustableStore returns an AdjustableStore allowing requestsPerSecond,
+// enforced by a store from newStore.
+func NewAdjustableStore(requestsPerSecond float64, newStore func(requestsPerSecond float64) echomw.RateLimiterStore) *AdjustableStore {
+	return &AdjustableStore{
+		rps:      requestsPerSecond,
+		store:    newStore(requestsPerSecond),
+		newStore: newStore,
+	}
+}
+
+// Allow reports whether the current store allows a request from identifier.
+func (s *AdjustableStore) Allow(identifier string) (bool, error) {
+	s.mu.RLock()
+	store := s.store
+	s.mu.RUnlock()
+	return store.Allow(identifier)
+}
+
+// Rate returns the current requests-per-second limit.
+func (s *AdjustableStore) Rate() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rps
+}
+
+// SetRate replaces the limit with requestsPerSecond.
+func (s *AdjustableStore) SetRate(requestsPerSecond float64) {
+	store := s.newStore(requestsPerSecond)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rps = requestsPerSecond
+	s.store = store
+}
+
+// AllStores is an echo RateLimiterStore that admits a request only when every
+// store admits it. Stores are consulted in order and the first rejection stops
+// the check, so list the store with the shortest window first: a request it
+// rejects is then not counted against the longer windows.
+//
+// The reverse does not hold. RateLimiterStore has no way to check without
+// consuming, so a request admitted by an earlier store but rejected by a
+// later one has already used up its token there. A client pinned at its
+// per-minute limit therefore also drains its per-second allowance. That only
+// matters while the longer window is rejecting anyway, so it is accepted
+// rather than replacing echo's stores.
+type AllStores []echomw.RateLimiterStore
+
+// Allow reports whether every store allows a request from identifier.
+func (s AllStores) Allow(identifier string) (bool, error) {
+	for _, store := range s {
+		ok, err := store.Allow(identifier)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// PathPrefixSkipper returns an echo Skipper that skips requests whose path
+// equals or is nested under one of prefixes, e.g. to exempt them from rate
+// limiting.
+func PathPrefixSkipper(prefixes []string) echomw.Skipper {
+	return func(c echo.Context) bool {
+		path := c.Request().URL.Path
+		for _, p := range prefixes {
+			if model.PathUnder(path, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+	"golang.org/x/time/rate"
+)
+
+// fakeClock is a manually advanced time source for SlidingWindowStore.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func allowN(t *testing.T, store echomw.RateLimiterStore, n int) int {
+	t.Helper()
+	allowed := 0
+	for range n {
+		ok, err := store.Allow("192.0.2.1")
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestSlidingWindowStore(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	store := NewSlidingWindowStore(10)
+	store.now = clock.now
+
+	if got := allowN(t, store, 15); got != 10 {
+		t.Fatalf("first window: allowed %d, want 10", got)
+	}
+
+	// Halfway through the same window the limit is still exhausted.
+	clock.advance(500 * time.Millisecond)
+	if got := allowN(t, store, 5); got != 0 {
+		t.Errorf("same window: allowed %d, want 0", got)
+	}
+
+	// 0.75s into the next window, a quarter of the previous window's 10
+	// requests still overlaps (2.5), leaving room for 8 more.
+	clock.advance(1250 * time.Millisecond)
+	if got := allowN(t, store, 10); got != 8 {
+		t.Errorf("next window: allowed %d, want 8", got)
+	}
+
+	// After two idle windows the full limit is available again.
+	clock.advance(2 * time.Second)
+	if got := allowN(t, store, 15); got != 10 {
+		t.Errorf("after idle: allowed %d, want 10", got)
+	}
+}
+
+func TestSlidingWindowStore_PerIdentifier(t *testing.T) {
+	store := NewSlidingWindowStore(1)
+
+	for _, id := range []string{"192.0.2.1", "192.0.2.2"} {
+		if ok, _ := store.Allow(id); !ok {
+			t.Errorf("first request from %s rejected", id)
+		}
+	}
+	if ok, _ := store.Allow("192.0.2.1"); ok {
+		t.Error("second request from 192.0.2.1 allowed, want rejected")
+	}
+}
+
+// TestSlidingWindowStore_NoBurstRefill contrasts the two algorithms: after the
+// limit is used up, a token bucket refills continuously while the sliding
+// window admits nothing more until its window moves on.
+func TestSlidingWindowStore_NoBurstRefill(t *testing.T) {
+	bucket := echomw.NewRateLimiterMemoryStore(rate.Limit(20))
+	window := NewSlidingWindowStore(20)
+
+	if got := allowN(t, bucket, 20); got != 20 {
+		t.Fatalf("token bucket burst: allowed %d, want 20", got)
+	}
+	if got := allowN(t, window, 20); got != 20 {
+		t.Fatalf("sliding window: allowed %d, want 20", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if got := allowN(t, bucket, 10); got < 2 {
+		t.Errorf("token bucket after 200ms: allowed %d, want refilled tokens", got)
+	}
+	if got := allowN(t, window, 10); got != 0 {
+		t.Errorf("sliding window after 200ms: allowed %d, want 0", got)
+	}
+}
+
+func TestAllStores_MinuteCapsBursts(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	second := NewSlidingWindowStore(10)
+	second.now = clock.now
+	minute := NewMinuteWindowStore(25)
+	minute.now = clock.now
+	store := AllStores{second, minute}
+
+	// A full burst fits in both limits.
+	if got := allowN(t, store, 15); got != 10 {
+		t.Fatalf("first second: allowed %d, want 10", got)
+	}
+	clock.advance(2 * time.Second)
+	if got := allowN(t, store, 15); got != 10 {
+		t.Fatalf("third second: allowed %d, want 10", got)
+	}
+
+	// The per-second limit has room again, but only 5 remain for the minute.
+	clock.advance(2 * time.Second)
+	if got := allowN(t, store, 15); got != 5 {
+		t.Errorf("fifth second: allowed %d, want 5", got)
+	}
+	clock.advance(2 * time.Second)
+	if got := allowN(t, store, 15); got != 0 {
+		t.Errorf("minute exhausted: allowed %d, want 0", got)
+	}
+
+	// Requests the per-second limit rejects are not counted for the minute.
+	if n := minute.counters["192.0.2.1"].current; n != 25 {
+		t.Errorf("minute window counted %v requests, want 25", n)
+	}
+}
+
+func TestAdjustableStore_SetRate(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	store := NewAdjustableStore(10, func(rps float64) echomw.RateLimiterStore {
+		s := NewSlidingWindowStore(rps)
+		s.now = clock.now
+		return s
+	})
+
+	if got := allowN(t, store, 15); got != 10 {
+		t.Fatalf("initial rate: allowed %d, want 10", got)
+	}
+
+	// Tightening the limit applies at once, with counters reset.
+	store.SetRate(3)
+	if got := store.Rate(); got != 3 {
+		t.Errorf("Rate() = %v, want 3", got)
+	}
+	if got := allowN(t, store, 5); got != 3 {
+		t.Errorf("after SetRate(3): allowed %d, want 3", got)
+	}
+}
+
+func TestPathPrefixSkipper_ExemptsPaths(t *testing.T) {
+	e := echo.New()
+	e.Use(echomw.RateLimiterWithConfig(echomw.RateLimiterConfig{
+		Skipper: PathPrefixSkipper([]string{"/healthz", "/internal/"}),
+		Store:   NewSlidingWindowStore(1),
+	}))
+	ok := func(c echo.Context) error { return c.String(http.StatusOK, "ok") }
+	e.GET("/healthz", ok)
+	e.GET("/internal/*", ok)
+	e.GET("/api/v3/*", ok)
+
+	tests := []struct {
+		path        string
+		wantLimited bool
+	}{
+		{"/healthz", false},
+		{"/internal/stats", false},
+		{"/healthzz", true},
+		{"/api/v3/search/lucene/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			limited := false
+			for range 3 {
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, http.NoBody))
+				if rec.Code == http.StatusTooManyRequests {
+					limited = true
+				}
+			}
+			if limited != tt.wantLimited {
+				t.Errorf("rate limited = %v, want %v", limited, tt.wantLimited)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+// MaxCorrelationIDLength bounds a client-supplied correlation ID. Longer
+// values are ignored, so clients cannot bloat every log line and header.
+const MaxCorrelationIDLength = 128
+
+// RequestID returns Echo's request ID middleware extended to honor a
+// client-supplied correlation ID. When the request carries a well-formed
+// value in header (see ValidCorrelationID), it becomes the request ID used in
+// logs and error bodies; otherwise Echo's usual ID is used. An inbound
+// X-Request-Id is held to the same bound and dropped when it fails it, so
+// Echo generates a fresh ID instead. Either way the ID
+// in effect is echoed in the response as both X-Request-Id and header, and
+// written back to the request's header so that it is forwarded upstream.
+func RequestID(header string) echo.MiddlewareFunc {
+	requestID := echomw.RequestIDWithConfig(echomw.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, id string) {
+			c.Request().Header.Set(header, id)
+			c.Response().Header().Set(header, id)
+		},
+	})
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		h := requestID(next)
+		return func(c echo.Context) error {
+			req := c.Request()
+			if id := req.Header.Get(echo.HeaderXRequestID); id != "" && !ValidCorrelationID(id) {
+				req.Header.Del(echo.HeaderXRequestID)
+			}
+			if id := req.Header.Get(header); ValidCorrelationID(id) {
+				req.Header.Set(echo.HeaderXRequestID, id)
+			}
+			return h(c)
+		}
+	}
+}
+
+// ValidCorrelationID reports whether id is 1 to MaxCorrelationIDLength
+// characters of ASCII letters, digits, '-', '_', '.' and ':'.
+func ValidCorrelationID(id string) bool {
+	if id == "" || len(id) > MaxCorrelationIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantClient bool // the client's value becomes the request ID
+	}{
+		{"well-formed ID used", "trace-42:abc_DEF.1", true},
+		{"absent falls back", "", false},
+		{"too long falls back", strings.Repeat("a", MaxCorrelationIDLength+1), false},
+		{"maximum length used", strings.Repeat("a", MaxCorrelationIDLength), true},
+		{"disallowed characters fall back", "id with spaces", false},
+		{"non-ASCII falls back", "идентификатор", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string // correlation header as seen by the handler
+			e := echo.New()
+			e.Use(RequestID("X-Correlation-Id"))
+			e.GET("/", func(c echo.Context) error {
+				seen = c.Request().Header.Get("X-Correlation-Id")
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			if tt.header != "" {
+				req.Header.Set("X-Correlation-Id", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if id == "" {
+				t.Fatal("X-Request-Id missing from response")
+			}
+			if got := id == tt.header; got != tt.wantClient {
+				t.Errorf("X-Request-Id = %q, client value used = %v, want %v", id, got, tt.wantClient)
+			}
+			if got := rec.Header().Get("X-Correlation-Id"); got != id {
+				t.Errorf("response X-Correlation-Id = %q, want %q", got, id)
+			}
+			if seen != id {
+				t.Errorf("request X-Correlation-Id = %q, want %q", seen, id)
+			}
+		})
+	}
+}
+
+func TestRequestID_InboundRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		wantClient bool // the client's X-Request-Id is kept
+	}{
+		{"well-formed ID kept", "req-42", true},
+		{"too long replaced", strings.Repeat("a", 8<<10), false},
+		{"non-ASCII replaced", "идентификатор", false},
+		{"disallowed characters replaced", "id\twith\ttabs", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string // correlation header as seen by the handler
+			e := echo.New()
+			e.Use(RequestID("X-Correlation-Id"))
+			e.GET("/", func(c echo.Context) error {
+				seen = c.Request().Header.Get("X-Correlation-Id")
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			if got := id == tt.requestID; got != tt.wantClient {
+				t.Errorf("X-Request-Id = %q, client value kept = %v, want %v", id, got, tt.wantClient)
+			}
+			if !ValidCorrelationID(id) {
+				t.Errorf("X-Request-Id = %q is not a valid ID", id)
+			}
+			if seen != id {
+				t.Errorf("request X-Correlation-Id = %q, want %q", seen, id)
+			}
+		})
+	}
+}
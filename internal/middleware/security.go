@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/labstack/echo/v4"
 )
 
@@ -16,9 +19,10 @@ var hopByHopHeaders = []string{
 	"Upgrade",
 }
 
-// SecurityHeaders returns an Echo middleware that adds security headers
-// and strips hop-by-hop headers from responses.
-func SecurityHeaders() echo.MiddlewareFunc {
+// SecurityHeaders returns an Echo middleware that sets the given security
+// headers (e.g. X-Frame-Options) on responses and strips hop-by-hop headers
+// from requests.
+func SecurityHeaders(headers map[string]string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Strip hop-by-hop headers from incoming request
@@ -29,10 +33,43 @@ func SecurityHeaders() echo.MiddlewareFunc {
 			err := next(c)
 
 			// Add security headers to response
-			c.Response().Header().Set("X-Content-Type-Options", "nosniff")
-			c.Response().Header().Set("X-Frame-Options", "DENY")
+			for name, value := range headers {
+				c.Response().Header().Set(name, value)
+			}
 
 			return err
 		}
 	}
 }
+
+// RejectUpgrades returns an Echo middleware that answers connection upgrade
+// requests (e.g. WebSocket) with 501. Upgrade is hop-by-hop and stripped by
+// SecurityHeaders, so without this check the request would reach upstream as
+// a plain, broken request. It must be installed before SecurityHeaders.
+//
+// An Upgrade offer naming only h2c is let through: servers may ignore it and
+// answer over HTTP/1.1.
+func RejectUpgrades() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			for proto := range strings.SplitSeq(c.Request().Header.Get("Upgrade"), ",") {
+				if p := strings.TrimSpace(proto); p != "" && !strings.EqualFold(p, "h2c") {
+					return echo.NewHTTPError(http.StatusNotImplemented, "connection upgrades are not supported by this proxy")
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// ResponseHeader returns an Echo middleware that sets header name to value
+// on every response, e.g. X-Proxy-Version. The header is set before the
+// handler runs so that streamed and error responses carry it too.
+func ResponseHeader(name, value string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(name, value)
+			return next(c)
+		}
+	}
+}
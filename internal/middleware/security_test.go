@@ -9,27 +9,51 @@ import (
 )
 
 func TestSecurityHeaders_AddsHeaders(t *testing.T) {
-	e := echo.New()
-	e.Use(SecurityHeaders())
-	e.GET("/test", func(c echo.Context) error {
-		return c.String(http.StatusOK, "ok")
-	})
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    map[string]string // "" = header absent
+	}{
+		{
+			name:    "defaults",
+			headers: map[string]string{"X-Frame-Options": "DENY", "X-Content-Type-Options": "nosniff"},
+			want:    map[string]string{"X-Frame-Options": "DENY", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name:    "override and disable",
+			headers: map[string]string{"X-Frame-Options": "SAMEORIGIN"},
+			want:    map[string]string{"X-Frame-Options": "SAMEORIGIN", "X-Content-Type-Options": ""},
+		},
+		{
+			name: "none",
+			want: map[string]string{"X-Frame-Options": "", "X-Content-Type-Options": ""},
+		},
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/test", http.NoBody)
-	rec := httptest.NewRecorder()
-	e.ServeHTTP(rec, req)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(SecurityHeaders(tt.headers))
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
 
-	if v := rec.Header().Get("X-Content-Type-Options"); v != "nosniff" {
-		t.Errorf("X-Content-Type-Options = %q, want %q", v, "nosniff")
-	}
-	if v := rec.Header().Get("X-Frame-Options"); v != "DENY" {
-		t.Errorf("X-Frame-Options = %q, want %q", v, "DENY")
+			req := httptest.NewRequest(http.MethodGet, "/test", http.NoBody)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			for name, want := range tt.want {
+				if v := rec.Header().Get(name); v != want {
+					t.Errorf("%s = %q, want %q", name, v, want)
+				}
+			}
+		})
 	}
 }
 
 func TestSecurityHeaders_StripsHopByHop(t *testing.T) {
 	e := echo.New()
-	e.Use(SecurityHeaders())
+	e.Use(SecurityHeaders(nil))
 
 	var gotConnection string
 	e.GET("/test", func(c echo.Context) error {
@@ -47,3 +71,71 @@ func TestSecurityHeaders_StripsHopByHop(t *testing.T) {
 		t.Errorf("Connection header should be stripped, got %q", gotConnection)
 	}
 }
+
+func TestRejectUpgrades(t *testing.T) {
+	tests := []struct {
+		name     string
+		upgrade  string
+		wantCode int
+	}{
+		{"websocket", "websocket", http.StatusNotImplemented},
+		{"mixed offer", "h2c, websocket", http.StatusNotImplemented},
+		{"h2c offer ignored", "h2c", http.StatusOK},
+		{"no upgrade", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(RejectUpgrades())
+			e.Use(SecurityHeaders(nil))
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", http.NoBody)
+			if tt.upgrade != "" {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", tt.upgrade)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestResponseHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{"handled", "/test", http.StatusOK},
+		{"error response", "/missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(ResponseHeader("X-Proxy-Version", "1.2.3"))
+			e.GET("/test", func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, http.NoBody)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if v := rec.Header().Get("X-Proxy-Version"); v != "1.2.3" {
+				t.Errorf("X-Proxy-Version = %q, want %q", v, "1.2.3")
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package model
+
+import "strings"
+
+// PathUnder reports whether path equals prefix or is nested under it, so
+// "/api/v4/audit" matches "/api/v4/audit/linux" but not "/api/v4/auditing".
+func PathUnder(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
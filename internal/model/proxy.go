@@ -6,16 +6,21 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // ProxyRequest represents a client request to be forwarded upstream.
 type ProxyRequest struct {
 	Ctx    context.Context
 	Method string
+	Host   string // client-supplied Host header
 	Path   string
 	Query  url.Values
 	Header http.Header
 	Body   io.ReadCloser
+	// ContentLength is the declared body length; -1 means unknown, as with
+	// a chunked body, which is streamed and cannot be replayed.
+	ContentLength int64
 }
 
 // ProxyResponse represents the upstream response to be streamed back.
@@ -23,4 +28,6 @@ type ProxyResponse struct {
 	StatusCode int
 	Header     http.Header
 	Body       io.ReadCloser
+	// Duration is the time the upstream took to return response headers.
+	Duration time.Duration
 }
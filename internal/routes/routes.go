@@ -0,0 +1,29 @@
+// Package routes defines the URL paths served by the proxy. It has no
+// dependencies, so both route registration and config validation can use it
+// without an import cycle.
+package routes
+
+// Fixed routes.
+const (
+	Healthz      = "/healthz"
+	Status       = "/proxy/status"
+	Maintenance  = "/proxy/maintenance"
+	MetricsJSON  = "/proxy/metrics.json"
+	AllowedHosts = "/proxy/allowed-hosts"
+	RateLimit    = "/proxy/ratelimit"
+	DebugHeaders = "/proxy/debug/headers"
+	InFlight     = "/proxy/inflight"
+)
+
+// Proxied API prefixes. Everything below them is forwarded upstream.
+const (
+	APIv3 = "/api/v3"
+	APIv4 = "/api/v4"
+)
+
+// ReservedPrefixes returns the paths owned by the proxy's own routes. A
+// configurable path such as metrics.path must not equal any of them or be
+// nested under one.
+func ReservedPrefixes() []string {
+	return []string{APIv3, APIv4, Healthz, Status, Maintenance, MetricsJSON, AllowedHosts, RateLimit, DebugHeaders, InFlight}
+}
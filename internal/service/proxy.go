@@ -2,26 +2,112 @@
 package service
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"vulners-proxy-go/internal/client"
 	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
 	"vulners-proxy-go/internal/model"
 )
 
 // ErrMissingAPIKey is returned when no API key is available from config or request header.
 var ErrMissingAPIKey = errors.New("API key required: set vulners.api_key in config or send X-Api-Key header")
 
+// ErrMissingContentType is returned for a POST, PUT or PATCH without a
+// Content-Type header when server.require_content_type_on_post is set.
+var ErrMissingContentType = errors.New("missing Content-Type header: required for POST, PUT and PATCH requests")
+
+// ErrUnexpectedBody is returned for a GET, HEAD or DELETE with a body when
+// server.reject_get_body is set.
+var ErrUnexpectedBody = errors.New("request body not allowed for GET, HEAD and DELETE requests")
+
+// ErrHeadersTooLarge is returned when the filtered upstream request headers
+// exceed upstream.max_header_bytes.
+var ErrHeadersTooLarge = errors.New("request headers too large to forward upstream")
+
+// ErrUnexpectedRedirect is returned when upstream answers with a redirect and
+// upstream.on_redirect is "error".
+var ErrUnexpectedRedirect = errors.New("unexpected upstream redirect")
+
+// ErrInvalidUpstreamURL is returned when the constructed upstream URL fails
+// the final sanity check. Used directly, it means the URL no longer targets
+// the upstream host, which is a proxy bug; failures caused by the client's
+// request wrap it as ErrInvalidRequestPath or ErrUpstreamURLTooLong.
+var ErrInvalidUpstreamURL = errors.New("invalid upstream URL")
+
+// ErrInvalidRequestPath is returned when the request path contains dot
+// segments or control characters, or is not absolute.
+var ErrInvalidRequestPath = fmt.Errorf("%w: invalid request path", ErrInvalidUpstreamURL)
+
+// ErrUpstreamURLTooLong is returned when the upstream URL would exceed
+// maxUpstreamURLLength, usually because of a long query string.
+var ErrUpstreamURLTooLong = fmt.Errorf("%w: too long", ErrInvalidUpstreamURL)
+
+// maxUpstreamURLLength bounds constructed upstream URLs. Rewrite or
+// default-parameter mistakes that grow the URL on every hop fail here
+// instead of producing ever longer requests.
+const maxUpstreamURLLength = 8192
+
+// MissingParamsError is returned when a request lacks query parameters that
+// server.required_params marks as mandatory for its path.
+type MissingParamsError struct {
+	Params []string
+}
+
+func (e *MissingParamsError) Error() string {
+	return "missing required query parameters: " + strings.Join(e.Params, ", ")
+}
+
 // allowedUpstreamHosts restricts which hosts the proxy will forward to.
 var allowedUpstreamHosts = map[string]bool{
 	"vulners.com": true,
 }
 
+// HostNotAllowedError is returned when an upstream host is not in the
+// allowlist.
+type HostNotAllowedError struct {
+	Host string
+}
+
+func (e *HostNotAllowedError) Error() string {
+	return fmt.Sprintf("upstream host %q is not in the allowlist", e.Host)
+}
+
+// AllowedUpstreamHosts returns the upstream host allowlist, sorted.
+func AllowedUpstreamHosts() []string {
+	return slices.Sorted(maps.Keys(allowedUpstreamHosts))
+}
+
+// isAllowedHost reports whether host may be used as an upstream. Matching
+// ignores case and a trailing dot; ports must already be stripped.
+func isAllowedHost(host string) bool {
+	return allowedUpstreamHosts[strings.TrimSuffix(strings.ToLower(host), ".")]
+}
+
+// checkUpstreamHost returns a *HostNotAllowedError unless host is allowed.
+// It is used at startup for base_url and must also guard any upstream
+// chosen per request.
+func checkUpstreamHost(host string) error {
+	if !isAllowedHost(host) {
+		return &HostNotAllowedError{Host: host}
+	}
+	return nil
+}
+
 // forwardableRequestHeaders are the only request headers forwarded upstream.
 // Note: X-Real-Ip and X-Forwarded-For are intentionally excluded to prevent
 // clients from injecting arbitrary identity information into upstream requests.
@@ -33,6 +119,31 @@ var forwardableRequestHeaders = []string{
 	"Content-Length",
 }
 
+// neverForwardedHeaders are request headers that no forward_header_prefixes
+// entry can forward: credentials, client identity headers, and hop-by-hop
+// headers. X-Forwarded-* is matched separately by neverForwarded.
+var neverForwardedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Forwarded":           true,
+	"X-Real-Ip":           true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Connection":    true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
+// neverForwarded reports whether the canonical header name canon must not be
+// forwarded through a configured prefix.
+func neverForwarded(canon string) bool {
+	return neverForwardedHeaders[canon] || strings.HasPrefix(canon, "X-Forwarded-")
+}
+
 // forwardableResponseHeaders are the only response headers forwarded to the client.
 var forwardableResponseHeaders = map[string]bool{
 	"Content-Type":     true,
@@ -45,84 +156,491 @@ var forwardableResponseHeaders = map[string]bool{
 
 const userAgent = "vulners-proxy-go/1.0"
 
+// canaryHeader selects the canary upstream when set to "true" and
+// upstream.canary_base_url is configured.
+const canaryHeader = "X-Canary"
+
 // ProxyService handles the forwarding logic for proxy requests.
 type ProxyService struct {
 	client  *client.VulnersClient
 	cfg     *config.Config
 	logger  *slog.Logger
+	metrics *metrics.Metrics // nil when metrics are disabled
 	baseURL *url.URL
+	canary  *url.URL // upstream.canary_base_url; nil when unset
+
+	firstByteTimeout  time.Duration // 0 disables the time-to-first-byte bound
+	pathTimeouts      []pathTimeout // per-path overrides of firstByteTimeout, longest prefix first
+	headerPrefixes    []string      // lowercase request header prefixes forwarded as-is
+	defaultAccept     string        // sent when the client omits Accept; empty disables
+	acceptEncoding    string        // replaces the client's Accept-Encoding; empty keeps it
+	correlationHeader string        // canonical request ID header forwarded upstream; empty disables
+	injectLatency     time.Duration // debug-only delay before forwarding; 0 disables
+	apiKeyHeader      string        // canonical client header carrying the API key
+	tenantHeader      string        // canonical client header selecting a tenant key; empty when none are configured
+	statusRemap       map[int]int   // upstream.status_remap with parsed keys; nil when empty
+	shadow            *shadower     // nil when shadowing is disabled
 }
 
 // NewProxyService creates a ProxyService.
-func NewProxyService(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger) (*ProxyService, error) {
-	u, err := url.Parse(cfg.Upstream.BaseURL)
+// The metrics parameter is optional; pass nil to disable metrics recording.
+func NewProxyService(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*ProxyService, error) {
+	s, err := newProxyService(c, cfg, logger, m)
 	if err != nil {
-		return nil, fmt.Errorf("parse upstream base_url: %w", err)
+		return nil, err
 	}
 
-	if !allowedUpstreamHosts[u.Hostname()] {
-		return nil, fmt.Errorf("upstream host %q is not in the allowlist", u.Hostname())
+	if err := checkUpstreamHost(s.baseURL.Hostname()); err != nil {
+		return nil, err
+	}
+	if s.canary != nil {
+		if err := checkUpstreamHost(s.canary.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	if s.shadow != nil {
+		if err := checkUpstreamHost(s.shadow.baseURL.Hostname()); err != nil {
+			return nil, err
+		}
 	}
 
-	return &ProxyService{
-		client:  c,
-		cfg:     cfg,
-		logger:  logger.With("component", "proxy_service"),
-		baseURL: u,
-	}, nil
+	return s, nil
 }
 
 // NewProxyServiceForTest creates a ProxyService without host allowlist validation.
 // This is intended only for tests that use httptest servers on localhost.
-func NewProxyServiceForTest(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger) (*ProxyService, error) {
+func NewProxyServiceForTest(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*ProxyService, error) {
+	return newProxyService(c, cfg, logger, m)
+}
+
+func newProxyService(c *client.VulnersClient, cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*ProxyService, error) {
 	u, err := url.Parse(cfg.Upstream.BaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse upstream base_url: %w", err)
 	}
 
+	var canary *url.URL
+	if cfg.Upstream.CanaryBaseURL != "" {
+		canary, err = url.Parse(cfg.Upstream.CanaryBaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream canary_base_url: %w", err)
+		}
+	}
+
+	prefixes := make([]string, 0, len(cfg.Upstream.ForwardHeaderPrefixes))
+	for _, p := range cfg.Upstream.ForwardHeaderPrefixes {
+		prefixes = append(prefixes, strings.ToLower(p))
+	}
+
+	keyHeader := cfg.Vulners.APIKeyHeader
+	if keyHeader == "" {
+		keyHeader = config.UpstreamAPIKeyHeader
+	}
+
+	pathTimeouts := make([]pathTimeout, 0, len(cfg.Upstream.PathTimeouts))
+	for prefix, d := range cfg.Upstream.PathTimeouts {
+		pathTimeouts = append(pathTimeouts, pathTimeout{prefix: prefix, timeout: d.Std()})
+	}
+	slices.SortFunc(pathTimeouts, func(a, b pathTimeout) int {
+		return cmp.Or(cmp.Compare(len(b.prefix), len(a.prefix)), cmp.Compare(a.prefix, b.prefix))
+	})
+
+	var shadow *shadower
+	if cfg.Shadow.Enabled {
+		shadow, err = newShadower(cfg, logger, m)
+		if err != nil {
+			return nil, err
+		}
+		shadow.logger.Info("shadowing GET requests", "shadow_url", shadow.baseURL.Redacted())
+	}
+	if canary != nil {
+		logger.Info("routing X-Canary requests to canary upstream", "canary_url", canary.Redacted())
+	}
+
+	var statusRemap map[int]int
+	for from, to := range cfg.Upstream.StatusRemap {
+		code, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("parse upstream.status_remap key %q: %w", from, err)
+		}
+		if statusRemap == nil {
+			statusRemap = make(map[int]int, len(cfg.Upstream.StatusRemap))
+		}
+		statusRemap[code] = to
+	}
+
+	var tenantHeader string
+	if len(cfg.Vulners.TenantKeys) > 0 {
+		tenantHeader = http.CanonicalHeaderKey(cmp.Or(cfg.Vulners.TenantHeader, "X-Tenant-Id"))
+	}
+
+	logger = logger.With("component", "proxy_service")
+	injectLatency := cfg.Debug.LatencyInjection()
+	if injectLatency > 0 {
+		logger.Warn("debug latency injection enabled; every proxied request is delayed (testing only)",
+			"latency", injectLatency,
+		)
+	}
+
 	return &ProxyService{
-		client:  c,
-		cfg:     cfg,
-		logger:  logger.With("component", "proxy_service"),
-		baseURL: u,
+		client:            c,
+		cfg:               cfg,
+		logger:            logger,
+		metrics:           m,
+		baseURL:           u,
+		canary:            canary,
+		firstByteTimeout:  cfg.Upstream.Timeout.Std(),
+		pathTimeouts:      pathTimeouts,
+		headerPrefixes:    prefixes,
+		defaultAccept:     cfg.Upstream.DefaultAccept,
+		acceptEncoding:    cfg.Upstream.ForceAcceptEncoding,
+		correlationHeader: http.CanonicalHeaderKey(cfg.Server.CorrelationIDHeader),
+		injectLatency:     injectLatency,
+		apiKeyHeader:      http.CanonicalHeaderKey(keyHeader),
+		tenantHeader:      tenantHeader,
+		statusRemap:       statusRemap,
+		shadow:            shadow,
 	}, nil
 }
 
 // Forward sends a ProxyRequest to the upstream Vulners API and returns the response.
 // The caller is responsible for closing the response body.
 //
-// The API key is resolved in order: config value → X-Api-Key request header.
-// If neither is present, ErrMissingAPIKey is returned.
+// The API key is resolved in order: vulners.tenant_keys entry selected by the
+// vulners.tenant_header request header → config value →
+// vulners.api_key_header request header (X-Api-Key by default), with the last
+// two swapped when vulners.key_precedence is "header".
+// If none is present, ErrMissingAPIKey is returned. When a secondary key
+// is configured and upstream rejects the primary config key with 401, the
+// request is retried once with the secondary key.
+//
+// Requests carrying "X-Canary: true" go to upstream.canary_base_url when it
+// is configured, and to upstream.base_url otherwise.
+//
+// Status codes listed in upstream.status_remap are replaced before redirect
+// handling; the secondary key retry and shadow comparison see the upstream's
+// own status.
 func (s *ProxyService) Forward(pr *model.ProxyRequest) (*model.ProxyResponse, error) {
 	apiKey := s.resolveAPIKey(pr.Header)
 	if apiKey == "" {
 		return nil, ErrMissingAPIKey
 	}
 
-	upstreamURL := s.buildUpstreamURL(pr.Path, pr.Query)
-	header := s.filterRequestHeaders(pr.Header)
-	header.Set("X-Api-Key", apiKey)
+	if missing := s.missingRequiredParams(pr.Path, pr.Query); len(missing) > 0 {
+		return nil, &MissingParamsError{Params: missing}
+	}
+
+	if s.cfg.Server.RequireContentTypeOnPost && pr.Header.Get("Content-Type") == "" {
+		switch pr.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			return nil, ErrMissingContentType
+		}
+	}
+
+	if s.cfg.Server.RejectGetBody && pr.ContentLength != 0 {
+		switch pr.Method {
+		case http.MethodGet, http.MethodHead, http.MethodDelete:
+			return nil, ErrUnexpectedBody
+		}
+	}
+
+	if s.cfg.Log.WarnQueryAPIKey {
+		s.warnQueryAPIKey(pr)
+	}
+	if _, ok := pr.Header["Cookie"]; ok {
+		s.noteCookies(pr)
+	}
+
+	base := s.upstreamBase(pr.Header)
+	upstreamURL, err := s.buildUpstreamURL(base, pr.Path, pr.Query)
+	if err != nil {
+		return nil, err
+	}
+	header := s.upstreamHeader(pr.Header, pr.Host, apiKey)
+	if limit := s.cfg.Upstream.MaxHeaderBytes; limit > 0 && headerSize(header) > limit {
+		return nil, ErrHeadersTooLarge
+	}
+
+	if s.injectLatency > 0 {
+		if err := sleepCtx(pr.Ctx, s.injectLatency); err != nil {
+			return nil, fmt.Errorf("injected latency: %w", err)
+		}
+	}
 
 	s.logger.Debug("forwarding request",
 		"method", pr.Method,
 		"path", pr.Path,
 	)
 
-	resp, err := s.client.DoStream(pr.Ctx, pr.Method, upstreamURL, header, pr.Body)
+	var body io.Reader = pr.Body
+	if pr.Body != nil && pr.Body != http.NoBody {
+		body = &bodyErrorReader{Reader: pr.Body, onError: func(err error) { s.recordBodyError(pr.Ctx, err) }}
+	}
+	// Tenant keys have no secondary; only the primary config key rotates.
+	rotate := s.canRotateKey() && apiKey == s.cfg.Vulners.APIKey
+	var replay []byte
+	if rotate && pr.Body != nil && pr.Body != http.NoBody && pr.ContentLength < 0 {
+		// A chunked body is streamed as it arrives, so it cannot be
+		// resent; a 401 for it is returned to the client as is.
+		rotate = false
+	}
+	if rotate && pr.Body != nil && pr.Body != http.NoBody {
+		// Buffer the body so it can be resent with the secondary key. The
+		// inbound body limit middleware bounds how much is read here.
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		replay = b
+		body = bytes.NewReader(replay)
+	}
+
+	resp, err := s.doWithFirstByteTimeout(pr.Ctx, pr.Path, pr.Method, upstreamURL, header, body)
 	if err != nil {
 		return nil, fmt.Errorf("forward to upstream: %w", err)
 	}
 
+	if rotate && resp.StatusCode == http.StatusUnauthorized {
+		_ = resp.Body.Close()
+		s.logger.Warn("upstream rejected primary API key; retrying with secondary key, rotate vulners.api_key",
+			"method", pr.Method,
+			"path", pr.Path,
+		)
+
+		header = header.Clone()
+		header.Set(config.UpstreamAPIKeyHeader, s.cfg.Vulners.SecondaryAPIKey)
+		if replay != nil {
+			body = bytes.NewReader(replay)
+		}
+		resp, err = s.doWithFirstByteTimeout(client.WithRetry(pr.Ctx), pr.Path, pr.Method, upstreamURL, header, body)
+		if err != nil {
+			return nil, fmt.Errorf("forward to upstream with secondary key: %w", err)
+		}
+	}
+
+	if s.shadow != nil && pr.Method == http.MethodGet {
+		if shadowURL, err := s.buildUpstreamURL(s.shadow.baseURL, pr.Path, pr.Query); err != nil {
+			s.shadow.fail(metrics.ShadowFailureError)
+		} else {
+			s.shadow.mirror(shadowURL, header, resp.StatusCode, resp.Duration)
+		}
+	}
+	if code, ok := s.statusRemap[resp.StatusCode]; ok {
+		resp.StatusCode = code
+	}
+
+	location := resp.Header.Get("Location")
 	resp.Header = s.filterResponseHeaders(resp.Header)
+	if isRedirect(resp.StatusCode) {
+		if err := s.handleRedirect(pr, resp, base, upstreamURL, location); err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+	}
+	if err := s.transformResponse(pr.Method, pr.Path, resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("transform response: %w", err)
+	}
+	return resp, nil
+}
+
+// isRedirect reports whether status is a 3xx redirect. 304 Not Modified is
+// a cache validation answer, not a redirect.
+func isRedirect(status int) bool {
+	return status >= 300 && status < 400 && status != http.StatusNotModified
+}
+
+// handleRedirect applies upstream.on_redirect to a redirect response. In
+// "error" mode it returns ErrUnexpectedRedirect; in "relay" mode it forwards
+// location only if it resolves to the upstream base itself or an allowed host.
+func (s *ProxyService) handleRedirect(pr *model.ProxyRequest, resp *model.ProxyResponse, base *url.URL, upstreamURL, location string) error {
+	target, err := url.Parse(upstreamURL)
+	if err == nil {
+		target, err = target.Parse(location)
+	}
+
+	if s.cfg.Upstream.OnRedirect != "relay" {
+		s.logger.Warn("unexpected upstream redirect",
+			"method", pr.Method,
+			"path", pr.Path,
+			"status", resp.StatusCode,
+			"location", sanitizeLocation(target, err),
+		)
+		return ErrUnexpectedRedirect
+	}
+
+	if location == "" {
+		return nil
+	}
+	if err != nil || (target.Host != base.Host && !isAllowedHost(target.Hostname())) {
+		s.logger.Warn("dropping upstream redirect Location to a host outside the allowlist",
+			"path", pr.Path,
+			"status", resp.StatusCode,
+			"location", sanitizeLocation(target, err),
+		)
+		return nil
+	}
+	resp.Header.Set("Location", location)
+	return nil
+}
+
+// sanitizeLocation renders a redirect target for logging without user info
+// or query string, either of which may carry credentials.
+func sanitizeLocation(u *url.URL, parseErr error) string {
+	if parseErr != nil || u == nil {
+		return "(invalid)"
+	}
+	clean := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	return clean.String()
+}
+
+// recordBodyError counts a failed read of the client's request body. A
+// canceled request context or a body cut short means the client went away;
+// anything else (e.g. the body limit) is reported as "other".
+func (s *ProxyService) recordBodyError(ctx context.Context, err error) {
+	reason := metrics.BodyErrorOther
+	if ctx.Err() != nil || errors.Is(err, io.ErrUnexpectedEOF) {
+		reason = metrics.BodyErrorClientDisconnect
+	}
+	s.logger.Debug("reading request body failed", "reason", reason, "err", err)
+	if s.metrics != nil {
+		s.metrics.RequestBodyErrors.WithLabelValues(reason).Inc()
+	}
+}
+
+// bodyErrorReader reports the first non-EOF error returned while reading
+// the client's request body, whether the service or the transport reads it.
+type bodyErrorReader struct {
+	io.Reader
+	onError  func(error)
+	reported bool
+}
+
+func (r *bodyErrorReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF && !r.reported {
+		r.reported = true
+		r.onError(err)
+	}
+	return n, err
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// canRotateKey reports whether a 401 from upstream should be retried with
+// the secondary API key. Keys supplied by clients are never rotated.
+func (s *ProxyService) canRotateKey() bool {
+	return s.cfg.Vulners.APIKey != "" && s.cfg.Vulners.SecondaryAPIKey != ""
+}
+
+// doWithFirstByteTimeout sends the upstream request, canceling it if no
+// response headers arrive within the first-byte timeout. Unlike a context
+// deadline, the bound is lifted once the response starts, so long streamed
+// bodies are not cut off. The returned body releases the request context on
+// Close.
+func (s *ProxyService) doWithFirstByteTimeout(ctx context.Context, path, method, upstreamURL string, header http.Header, body io.Reader) (*model.ProxyResponse, error) {
+	timeout := s.firstByteTimeoutFor(ctx, path)
+	if timeout <= 0 {
+		return s.client.DoStream(ctx, method, upstreamURL, header, body)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() {
+		cancel(context.DeadlineExceeded)
+	})
+
+	resp, err := s.client.DoStream(ctx, method, upstreamURL, header, body)
+	// A timer that already fired has canceled ctx, or is about to, even when
+	// the response arrived first; its body would fail mid-read, so the
+	// request counts as timed out either way.
+	if !timer.Stop() {
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		cancel(nil)
+		return nil, fmt.Errorf("no response within %s: %w", timeout, context.DeadlineExceeded)
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
-// resolveAPIKey returns the API key from config, falling back to the X-Api-Key request header.
+// pathTimeout is an upstream.path_timeouts entry.
+type pathTimeout struct {
+	prefix  string
+	timeout time.Duration
+}
+
+// firstByteTimeoutFor returns the first-byte bound for a request: the
+// configured timeout for path, shortened to the time left before ctx's
+// deadline so a client that gives up early does not keep an upstream request
+// waiting.
+func (s *ProxyService) firstByteTimeoutFor(ctx context.Context, path string) time.Duration {
+	timeout := s.firstByteTimeout
+	for _, pt := range s.pathTimeouts {
+		if model.PathUnder(path, pt.prefix) {
+			timeout = pt.timeout
+			break
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := max(time.Until(deadline), time.Nanosecond)
+		if timeout <= 0 || remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// resolveAPIKey returns the key of the tenant named in the tenant header,
+// falling back to the API key from config and the configured API key request
+// header, in the order set by vulners.key_precedence. The tenant key map is
+// never written after Load, so it is safe to read concurrently.
 func (s *ProxyService) resolveAPIKey(header http.Header) string {
-	if s.cfg.Vulners.APIKey != "" {
-		return s.cfg.Vulners.APIKey
+	if s.tenantHeader != "" {
+		if key, ok := s.cfg.Vulners.TenantKeys[header.Get(s.tenantHeader)]; ok {
+			return key
+		}
 	}
-	return header.Get("X-Api-Key")
+	if s.cfg.Vulners.KeyPrecedence == "header" {
+		return cmp.Or(header.Get(s.apiKeyHeader), s.cfg.Vulners.APIKey)
+	}
+	return cmp.Or(s.cfg.Vulners.APIKey, header.Get(s.apiKeyHeader))
+}
+
+// missingRequiredParams returns the configured required query parameters for
+// path that are absent or empty in query.
+func (s *ProxyService) missingRequiredParams(path string, query url.Values) []string {
+	required := s.cfg.Server.RequiredParams[path]
+	if required == nil {
+		if strings.HasSuffix(path, "/") {
+			required = s.cfg.Server.RequiredParams[strings.TrimSuffix(path, "/")]
+		} else {
+			required = s.cfg.Server.RequiredParams[path+"/"]
+		}
+	}
+
+	var missing []string
+	for _, p := range required {
+		if query.Get(p) == "" {
+			missing = append(missing, p)
+		}
+	}
+	return missing
 }
 
 // isSensitiveQueryParam reports whether the query parameter key is an API key
@@ -133,9 +651,66 @@ func isSensitiveQueryParam(key string) bool {
 	return lower == "apikey" || lower == "api_key"
 }
 
-func (s *ProxyService) buildUpstreamURL(path string, query url.Values) string {
-	u := *s.baseURL
-	u.Path = path
+// warnQueryAPIKey logs a warning when the request carries an API key in its
+// query string, to help move clients to the X-Api-Key header. Only parameter
+// names are logged, never values.
+func (s *ProxyService) warnQueryAPIKey(pr *model.ProxyRequest) {
+	var names []string
+	distinct := make(map[string]bool)
+	for k, vals := range pr.Query {
+		if !isSensitiveQueryParam(k) {
+			continue
+		}
+		names = append(names, k)
+		for _, v := range vals {
+			distinct[v] = true
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	slices.Sort(names)
+	s.logger.Warn("client sent API key in query string; use the X-Api-Key header instead (parameter stripped)",
+		"method", pr.Method,
+		"path", pr.Path,
+		"params", names,
+		"conflicting_values", len(distinct) > 1,
+	)
+}
+
+// upstreamBase returns the canary base URL for requests carrying
+// "X-Canary: true" when one is configured, and the primary base URL otherwise.
+func (s *ProxyService) upstreamBase(h http.Header) *url.URL {
+	if s.canary != nil && strings.EqualFold(strings.TrimSpace(h.Get(canaryHeader)), "true") {
+		return s.canary
+	}
+	return s.baseURL
+}
+
+// noteCookies records a request that arrived with a Cookie header. API
+// clients have no reason to send cookies, so these are usually browsers
+// calling the API directly. The cookies themselves are never forwarded.
+func (s *ProxyService) noteCookies(pr *model.ProxyRequest) {
+	s.logger.Debug("dropping request cookies",
+		"method", pr.Method,
+		"path", pr.Path,
+		"user_agent", pr.Header.Get("User-Agent"),
+	)
+	if s.metrics != nil {
+		s.metrics.RequestsWithCookies.Inc()
+	}
+}
+
+// buildUpstreamURL joins path onto the upstream base URL, strips API key query
+// parameters, and fills in configured default query parameters the client
+// did not supply. A path in the base URL is kept as a prefix: base
+// "https://host/api" and path "/api/v3/search/" give
+// "https://host/api/api/v3/search/". The result is checked by
+// validateUpstreamURL before it is returned.
+func (s *ProxyService) buildUpstreamURL(base *url.URL, path string, query url.Values) (string, error) {
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + path
+	u.RawPath = ""
 
 	q := make(url.Values)
 	for k, v := range query {
@@ -144,28 +719,140 @@ func (s *ProxyService) buildUpstreamURL(path string, query url.Values) string {
 		}
 		q[k] = v
 	}
+	for k, v := range s.cfg.Upstream.DefaultQueryParams {
+		if _, ok := q[k]; !ok {
+			q.Set(k, v)
+		}
+	}
 	u.RawQuery = q.Encode()
 
-	return u.String()
+	if err := validateUpstreamURL(base, &u); err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// validateUpstreamURL checks that u still targets the host of base with a
+// clean absolute path and a bounded length.
+func validateUpstreamURL(base, u *url.URL) error {
+	if u.Scheme != base.Scheme || u.Host != base.Host || u.User != nil {
+		return fmt.Errorf("%w: host %q does not match upstream %q", ErrInvalidUpstreamURL, u.Host, base.Host)
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		return fmt.Errorf("%w: path %q is not absolute", ErrInvalidRequestPath, u.Path)
+	}
+	for seg := range strings.SplitSeq(u.Path, "/") {
+		if seg == "." || seg == ".." {
+			return fmt.Errorf("%w: path %q contains dot segments", ErrInvalidRequestPath, u.Path)
+		}
+	}
+	if strings.ContainsFunc(u.Path, unicode.IsControl) {
+		return fmt.Errorf("%w: path contains control characters", ErrInvalidRequestPath)
+	}
+	if n := len(u.String()); n > maxUpstreamURLLength {
+		return fmt.Errorf("%w: length %d exceeds %d", ErrUpstreamURLTooLong, n, maxUpstreamURLLength)
+	}
+	return nil
+}
+
+// excludedHeader reports whether the canonical header name canon must not
+// be forwarded even when it is on forwardableRequestHeaders or matches a
+// configured prefix: the headers carrying the client's API key and tenant ID,
+// and those in neverForwardedHeaders.
+func (s *ProxyService) excludedHeader(canon string) bool {
+	return canon == s.apiKeyHeader || canon == s.tenantHeader || neverForwarded(canon)
 }
 
 func (s *ProxyService) filterRequestHeaders(src http.Header) http.Header {
 	dst := make(http.Header)
 	for _, key := range forwardableRequestHeaders {
+		if s.excludedHeader(key) {
+			continue
+		}
 		if vals := src.Values(key); len(vals) > 0 {
 			dst[http.CanonicalHeaderKey(key)] = vals
 		}
 	}
-	// Forward any X-Vulners-* headers
+	// Forward headers matching a configured prefix (X-Vulners-* by default).
 	for key, vals := range src {
-		if strings.HasPrefix(strings.ToLower(key), "x-vulners-") {
-			dst[http.CanonicalHeaderKey(key)] = vals
+		if s.excludedHeader(http.CanonicalHeaderKey(key)) {
+			continue
+		}
+		lower := strings.ToLower(key)
+		for _, prefix := range s.headerPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				dst[http.CanonicalHeaderKey(key)] = vals
+				break
+			}
+		}
+	}
+	if dst.Get("Accept") == "" && s.defaultAccept != "" {
+		dst.Set("Accept", s.defaultAccept)
+	}
+	if s.acceptEncoding != "" {
+		dst.Set("Accept-Encoding", s.acceptEncoding)
+	}
+	// The RequestID middleware has already replaced the client's value with
+	// the request ID in effect.
+	if s.correlationHeader != "" {
+		if id := src.Get(s.correlationHeader); id != "" {
+			dst.Set(s.correlationHeader, id)
 		}
 	}
 	dst.Set("User-Agent", userAgent)
 	return dst
 }
 
+// upstreamHeader returns the headers sent upstream for a request with the
+// given client header and Host, carrying apiKey as X-Api-Key.
+func (s *ProxyService) upstreamHeader(src http.Header, host, apiKey string) http.Header {
+	header := s.filterRequestHeaders(src)
+	header.Set(config.UpstreamAPIKeyHeader, apiKey)
+	if s.cfg.Upstream.PreserveHost && host != "" {
+		header.Set("Host", host)
+	}
+	return header
+}
+
+// UpstreamHeaders returns the headers Forward would send upstream for a
+// request with the given client header and Host. The API key is replaced by
+// "[REDACTED]", and X-Api-Key is absent when no key would be resolved.
+func (s *ProxyService) UpstreamHeaders(src http.Header, host string) http.Header {
+	apiKey := s.resolveAPIKey(src)
+	if apiKey != "" {
+		apiKey = "[REDACTED]"
+	}
+	header := s.upstreamHeader(src, host, apiKey)
+	if apiKey == "" {
+		header.Del(config.UpstreamAPIKeyHeader)
+	}
+	return header
+}
+
+// headerSize approximates the wire size of header as "Name: value\r\n" lines.
+func headerSize(header http.Header) int {
+	n := 0
+	for key, vals := range header {
+		for _, v := range vals {
+			n += len(key) + len(v) + 4
+		}
+	}
+	return n
+}
+
+// cancelOnClose releases the per-request upstream context once the caller
+// has finished reading the response body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
+
 func (s *ProxyService) filterResponseHeaders(src http.Header) http.Header {
 	dst := make(http.Header)
 	for key, vals := range src {
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"vulners-proxy-go/internal/client"
+	"vulners-proxy-go/internal/config"
+)
+
+// SelfTest sends a single GET for path to upstream, exercising DNS, TLS, and
+// the configured API key. Without a shared key in config there is nothing to
+// authenticate with, so any HTTP response counts as success. The caller
+// bounds the check with ctx.
+func (s *ProxyService) SelfTest(ctx context.Context, path string) error {
+	header := s.filterRequestHeaders(http.Header{})
+	if s.cfg.Vulners.APIKey != "" {
+		header.Set(config.UpstreamAPIKeyHeader, s.cfg.Vulners.APIKey)
+	}
+
+	upstreamURL, err := s.buildUpstreamURL(s.baseURL, path, nil)
+	if err != nil {
+		return fmt.Errorf("self-test: %w", err)
+	}
+	resp, err := s.client.DoStream(client.AsProbe(ctx), http.MethodGet, upstreamURL, header, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("self-test request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if s.cfg.Vulners.APIKey == "" {
+		return nil
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("self-test: upstream rejected vulners.api_key (status %d)", resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("self-test: unexpected upstream status %d", resp.StatusCode)
+	}
+	return nil
+}
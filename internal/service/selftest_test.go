@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vulners-proxy-go/internal/client"
+	"vulners-proxy-go/internal/config"
+)
+
+func TestSelfTest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/apiKey/valid/" {
+			t.Errorf("path = %q, want %q", r.URL.Path, "/api/v3/apiKey/valid/")
+		}
+		if r.Header.Get("X-Api-Key") != "good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		wantErr bool
+	}{
+		{"valid key", "good-key", false},
+		{"rejected key", "bad-key", true},
+		{"no shared key checks connectivity only", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Vulners: config.VulnersConfig{APIKey: tt.apiKey},
+				Upstream: config.UpstreamConfig{
+					BaseURL:         upstream.URL,
+					IdleConnections: 10,
+				},
+			}
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			svc, err := NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, nil)
+			if err != nil {
+				t.Fatalf("NewProxyServiceForTest: %v", err)
+			}
+
+			err = svc.SelfTest(context.Background(), "/api/v3/apiKey/valid/")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SelfTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelfTest_Unreachable(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	cfg := &config.Config{
+		Upstream: config.UpstreamConfig{BaseURL: upstream.URL, IdleConnections: 10},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc, err := NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, nil)
+	if err != nil {
+		t.Fatalf("NewProxyServiceForTest: %v", err)
+	}
+
+	if err := svc.SelfTest(context.Background(), "/api/v3/apiKey/valid/"); err == nil {
+		t.Error("SelfTest() error = nil, want connection error")
+	}
+}
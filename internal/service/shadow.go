@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+)
+
+// shadower mirrors proxied GET requests to a second upstream and records how
+// its answers compare with the primary's. It uses its own HTTP client, so
+// shadow traffic takes no upstream concurrency slots and is not counted in
+// the upstream metrics. Nothing it does can affect the client response.
+type shadower struct {
+	client  *http.Client
+	baseURL *url.URL
+	timeout time.Duration
+	slots   chan struct{} // one token per in-flight shadow request
+	logger  *slog.Logger
+	metrics *metrics.Metrics // nil when metrics are disabled
+
+	wg sync.WaitGroup // in-flight shadow requests; waited on by tests
+}
+
+func newShadower(cfg *config.Config, logger *slog.Logger, m *metrics.Metrics) (*shadower, error) {
+	u, err := url.Parse(cfg.Shadow.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse shadow base_url: %w", err)
+	}
+	return &shadower{
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: cfg.Shadow.MaxInFlight,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		baseURL: u,
+		timeout: cfg.Shadow.Timeout.Std(),
+		slots:   make(chan struct{}, cfg.Shadow.MaxInFlight),
+		logger:  logger.With("component", "shadow"),
+		metrics: m,
+	}, nil
+}
+
+// mirror sends a copy of a GET request, already sent to the primary with
+// header, to shadowURL in the background and compares the result with the
+// primary's status and time to headers. shadowURL is built on sh.baseURL like
+// the primary URL is built on upstream.base_url. When max_in_flight shadow
+// requests are already running, the request is dropped instead.
+func (sh *shadower) mirror(shadowURL string, header http.Header, primaryStatus int, primaryDuration time.Duration) {
+	select {
+	case sh.slots <- struct{}{}:
+	default:
+		sh.fail(metrics.ShadowFailureDropped)
+		return
+	}
+
+	u, err := url.Parse(shadowURL)
+	if err != nil {
+		<-sh.slots
+		sh.fail(metrics.ShadowFailureError)
+		return
+	}
+	header = header.Clone()
+	header.Del("Host")
+
+	sh.wg.Add(1)
+	go func() {
+		defer func() {
+			<-sh.slots
+			sh.wg.Done()
+		}()
+
+		// The client request may already be finished; the shadow request
+		// lives on its own deadline.
+		ctx, cancel := context.WithTimeout(context.Background(), sh.timeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+		if err != nil {
+			sh.fail(metrics.ShadowFailureError)
+			return
+		}
+		req.Header = header
+
+		start := time.Now()
+		resp, err := sh.client.Do(req)
+		elapsed := time.Since(start)
+		if err != nil {
+			sh.logger.Debug("shadow request failed", "path", u.Path, "err", err)
+			sh.fail(metrics.ShadowFailureError)
+			return
+		}
+		// Drain the body so the connection can be reused.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != primaryStatus {
+			sh.logger.Debug("shadow status mismatch",
+				"path", u.Path,
+				"primary_status", primaryStatus,
+				"shadow_status", resp.StatusCode,
+			)
+		}
+		if sh.metrics != nil {
+			sh.metrics.ShadowDuration.WithLabelValues(metrics.ShadowUpstreamPrimary).Observe(primaryDuration.Seconds())
+			sh.metrics.ShadowDuration.WithLabelValues(metrics.ShadowUpstreamShadow).Observe(elapsed.Seconds())
+			if resp.StatusCode != primaryStatus {
+				sh.metrics.ShadowMismatch.Inc()
+			}
+		}
+	}()
+}
+
+func (sh *shadower) fail(reason string) {
+	if sh.metrics != nil {
+		sh.metrics.ShadowFailures.WithLabelValues(reason).Inc()
+	}
+}
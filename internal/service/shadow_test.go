@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vulners-proxy-go/internal/client"
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/metrics"
+	"vulners-proxy-go/internal/model"
+)
+
+// shadowCounts returns the shadow mismatch count, failure counts by reason,
+// and the number of shadow latency samples.
+func shadowCounts(t *testing.T, m *metrics.Metrics) (mismatch float64, failures map[string]float64, samples uint64) {
+	t.Helper()
+	families, err := m.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	failures = make(map[string]float64)
+	for _, f := range families {
+		switch f.GetName() {
+		case "vulners_proxy_shadow_mismatch_total":
+			mismatch = f.GetMetric()[0].GetCounter().GetValue()
+		case "vulners_proxy_shadow_failures_total":
+			for _, metric := range f.GetMetric() {
+				failures[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+			}
+		case "vulners_proxy_shadow_duration_seconds":
+			for _, metric := range f.GetMetric() {
+				if metric.GetLabel()[0].GetValue() == metrics.ShadowUpstreamShadow {
+					samples = metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return mismatch, failures, samples
+}
+
+func TestForward_Shadow(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"source":"primary"}`))
+	}))
+	defer primary.Close()
+
+	var (
+		mu          sync.Mutex
+		shadowPaths []string
+	)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		shadowPaths = append(shadowPaths, r.URL.RequestURI())
+		mu.Unlock()
+		if r.Header.Get("X-Api-Key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v3/differs/":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/api/v3/slow/":
+			time.Sleep(200 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{"source":"shadow"}`))
+	}))
+	defer shadow.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         primary.URL,
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+		Shadow: config.ShadowConfig{
+			Enabled:     true,
+			BaseURL:     shadow.URL,
+			Timeout:     config.Duration(100 * time.Millisecond),
+			MaxInFlight: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := metrics.New()
+	svc, err := NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, m)
+	if err != nil {
+		t.Fatalf("NewProxyServiceForTest: %v", err)
+	}
+
+	forward := func(method, path string) {
+		t.Helper()
+		resp, err := svc.Forward(&model.ProxyRequest{
+			Ctx:    context.Background(),
+			Method: method,
+			Path:   path,
+			Query:  url.Values{"q": {"x"}},
+			Header: http.Header{},
+			Body:   http.NoBody,
+		})
+		if err != nil {
+			t.Fatalf("Forward(%s %s) error = %v", method, path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "primary") {
+			t.Errorf("%s %s: got %d %s, want 200 from primary", method, path, resp.StatusCode, body)
+		}
+	}
+
+	forward(http.MethodGet, "/api/v3/search/lucene/")
+	forward(http.MethodGet, "/api/v3/differs/")
+	forward(http.MethodGet, "/api/v3/slow/")
+	forward(http.MethodPost, "/api/v3/search/lucene/")
+	svc.shadow.wg.Wait()
+
+	mu.Lock()
+	got := slices.Clone(shadowPaths)
+	mu.Unlock()
+	if len(got) != 3 {
+		t.Errorf("shadow requests = %v, want the 3 GETs only", got)
+	}
+	for _, p := range got {
+		if !strings.HasSuffix(p, "?q=x") {
+			t.Errorf("shadow request %q lost the query string", p)
+		}
+	}
+
+	mismatch, failures, samples := shadowCounts(t, m)
+	if mismatch != 1 {
+		t.Errorf("shadow_mismatch_total = %v, want 1", mismatch)
+	}
+	if failures[metrics.ShadowFailureError] != 1 {
+		t.Errorf("shadow_failures_total{reason=error} = %v, want 1 (timeout)", failures[metrics.ShadowFailureError])
+	}
+	if samples != 2 {
+		t.Errorf("shadow duration samples = %d, want 2", samples)
+	}
+}
+
+func TestForward_ShadowBasePath(t *testing.T) {
+	var primaryPath string
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer primary.Close()
+
+	shadowPath := make(chan string, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shadowPath <- r.URL.RequestURI()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer shadow.Close()
+
+	cfg := &config.Config{
+		Vulners: config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{
+			BaseURL:         primary.URL + "/primary",
+			Timeout:         config.Duration(10 * time.Second),
+			IdleConnections: 10,
+		},
+		Shadow: config.ShadowConfig{
+			Enabled:     true,
+			BaseURL:     shadow.URL + "/shadow/",
+			Timeout:     config.Duration(time.Second),
+			MaxInFlight: 10,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc, err := NewProxyServiceForTest(client.NewVulnersClient(cfg, logger, nil), cfg, logger, nil)
+	if err != nil {
+		t.Fatalf("NewProxyServiceForTest: %v", err)
+	}
+
+	resp, err := svc.Forward(&model.ProxyRequest{
+		Ctx:    context.Background(),
+		Method: http.MethodGet,
+		Path:   "/api/v3/search/lucene/",
+		Query:  url.Values{"q": {"x"}},
+		Header: http.Header{},
+		Body:   http.NoBody,
+	})
+	if err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	svc.shadow.wg.Wait()
+
+	if primaryPath != "/primary/api/v3/search/lucene/" {
+		t.Errorf("primary path = %q, want /primary/api/v3/search/lucene/", primaryPath)
+	}
+	if got := <-shadowPath; got != "/shadow/api/v3/search/lucene/?q=x" {
+		t.Errorf("shadow request = %q, want /shadow/api/v3/search/lucene/?q=x", got)
+	}
+}
+
+func TestShadower_DropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	shadow := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-release
+	}))
+	defer shadow.Close()
+
+	cfg := &config.Config{
+		Shadow: config.ShadowConfig{BaseURL: shadow.URL, Timeout: config.Duration(5 * time.Second), MaxInFlight: 1},
+	}
+	m := metrics.New()
+	sh, err := newShadower(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), m)
+	if err != nil {
+		t.Fatalf("newShadower: %v", err)
+	}
+
+	sh.mirror(shadow.URL+"/api/v3/a/", http.Header{}, http.StatusOK, time.Millisecond)
+	sh.mirror(shadow.URL+"/api/v3/b/", http.Header{}, http.StatusOK, time.Millisecond)
+	close(release)
+	sh.wg.Wait()
+
+	if _, failures, _ := shadowCounts(t, m); failures[metrics.ShadowFailureDropped] != 1 {
+		t.Errorf("shadow_failures_total{reason=dropped} = %v, want 1", failures[metrics.ShadowFailureDropped])
+	}
+}
+
+func TestNewProxyService_ShadowHostAllowlist(t *testing.T) {
+	cfg := &config.Config{
+		Vulners:  config.VulnersConfig{APIKey: "test-key"},
+		Upstream: config.UpstreamConfig{BaseURL: "https://vulners.com"},
+		Shadow:   config.ShadowConfig{Enabled: true, BaseURL: "https://mirror.example.com", MaxInFlight: 1},
+	}
+	_, err := NewProxyService(nil, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	if _, ok := err.(*HostNotAllowedError); !ok {
+		t.Fatalf("NewProxyService() error = %v, want HostNotAllowedError", err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vulners-proxy-go/internal/model"
+)
+
+// transformResponse removes the configured top-level fields from JSON
+// responses on opted-in paths. Responses that are not JSON, are compressed,
+// or exceed the size cap are passed through untouched, so a failed transform
+// never changes what the client would otherwise have received.
+//
+// A transformed body gets a recomputed Content-Length. HEAD responses have no
+// body to measure, so the upstream length, which describes the untransformed
+// body, is removed instead.
+func (s *ProxyService) transformResponse(method, path string, resp *model.ProxyResponse) error {
+	rt := s.cfg.ResponseTransform
+	if !rt.Enabled || len(rt.StripFields) == 0 || !matchesPathPrefix(path, rt.Paths) {
+		return nil
+	}
+	if resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	if mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		return nil
+	}
+	if cl, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil && cl > rt.MaxBodyBytes {
+		return nil
+	}
+	if method == http.MethodHead {
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	// Read one byte past the cap to detect oversized bodies without a
+	// Content-Length; those are re-assembled and streamed as-is.
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, rt.MaxBodyBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(buf)) > rt.MaxBodyBytes {
+		resp.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(buf), resp.Body), Closer: resp.Body}
+		return nil
+	}
+
+	out := buf
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(buf, &obj) == nil {
+		for _, field := range rt.StripFields {
+			delete(obj, field)
+		}
+		if b, err := json.Marshal(obj); err == nil {
+			out = b
+		}
+	}
+
+	resp.Body = &readCloser{Reader: bytes.NewReader(out), Closer: resp.Body}
+	resp.Header.Set("Content-Length", strconv.Itoa(len(out)))
+	return nil
+}
+
+// matchesPathPrefix reports whether path equals or is nested under one of prefixes.
+func matchesPathPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if path == p || strings.HasPrefix(path, strings.TrimSuffix(p, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readCloser pairs a replacement body reader with the original body's Close.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vulners-proxy-go/internal/config"
+	"vulners-proxy-go/internal/model"
+)
+
+func TestTransformResponse(t *testing.T) {
+	s := &ProxyService{
+		cfg: &config.Config{
+			ResponseTransform: config.ResponseTransformConfig{
+				Enabled:      true,
+				Paths:        []string{"/api/v3/search/"},
+				StripFields:  []string{"highlight", "debug"},
+				MaxBodyBytes: 64,
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		encoding    string
+		body        string
+		wantFields  []string // expected top-level keys; nil means body must be unchanged
+	}{
+		{
+			name:        "strips configured fields",
+			path:        "/api/v3/search/lucene/",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"result":"OK","highlight":{},"debug":1}`,
+			wantFields:  []string{"result"},
+		},
+		{
+			name:        "path not opted in",
+			path:        "/api/v3/archive/collection/",
+			contentType: "application/json",
+			body:        `{"result":"OK","highlight":{}}`,
+		},
+		{
+			name:        "non-JSON content type",
+			path:        "/api/v3/search/lucene/",
+			contentType: "text/plain",
+			body:        `{"highlight":{}}`,
+		},
+		{
+			name:        "compressed body",
+			path:        "/api/v3/search/lucene/",
+			contentType: "application/json",
+			encoding:    "gzip",
+			body:        `{"highlight":{}}`,
+		},
+		{
+			name:        "invalid JSON passed through",
+			path:        "/api/v3/search/lucene/",
+			contentType: "application/json",
+			body:        `{"highlight":`,
+		},
+		{
+			name:        "JSON array passed through",
+			path:        "/api/v3/search/lucene/",
+			contentType: "application/json",
+			body:        `[{"highlight":{}}]`,
+		},
+		{
+			name:        "oversized body streamed untransformed",
+			path:        "/api/v3/search/lucene/",
+			contentType: "application/json",
+			body:        `{"result":"OK","highlight":"` + strings.Repeat("x", 100) + `"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Content-Type", tt.contentType)
+			header.Set("Content-Length", strconv.Itoa(len(tt.body)))
+			if tt.encoding != "" {
+				header.Set("Content-Encoding", tt.encoding)
+			}
+			resp := &model.ProxyResponse{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			if err := s.transformResponse(http.MethodGet, tt.path, resp); err != nil {
+				t.Fatalf("transformResponse() error = %v", err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+
+			if tt.wantFields == nil {
+				if string(got) != tt.body {
+					t.Errorf("body = %q, want unchanged %q", got, tt.body)
+				}
+				if cl := resp.Header.Get("Content-Length"); cl != strconv.Itoa(len(tt.body)) {
+					t.Errorf("Content-Length = %q, want upstream %d", cl, len(tt.body))
+				}
+				return
+			}
+
+			var obj map[string]json.RawMessage
+			if err := json.Unmarshal(got, &obj); err != nil {
+				t.Fatalf("unmarshal transformed body: %v", err)
+			}
+			if len(obj) != len(tt.wantFields) {
+				t.Errorf("fields = %v, want %v", obj, tt.wantFields)
+			}
+			for _, f := range tt.wantFields {
+				if _, ok := obj[f]; !ok {
+					t.Errorf("missing field %q", f)
+				}
+			}
+			if cl := resp.Header.Get("Content-Length"); cl != strconv.Itoa(len(got)) {
+				t.Errorf("Content-Length = %q, want %d", cl, len(got))
+			}
+		})
+	}
+}
+
+func TestTransformResponse_Disabled(t *testing.T) {
+	s := &ProxyService{cfg: &config.Config{}}
+	body := `{"highlight":{}}`
+	resp := &model.ProxyResponse{
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   io.NopCloser(strings.NewReader(body)),
+	}
+
+	if err := s.transformResponse(http.MethodGet, "/api/v3/search/lucene/", resp); err != nil {
+		t.Fatalf("transformResponse() error = %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != body {
+		t.Errorf("body = %q, want unchanged %q", got, body)
+	}
+}
+
+func TestTransformResponse_HEADDropsContentLength(t *testing.T) {
+	s := &ProxyService{
+		cfg: &config.Config{
+			ResponseTransform: config.ResponseTransformConfig{
+				Enabled:      true,
+				Paths:        []string{"/api/v3/search/"},
+				StripFields:  []string{"highlight"},
+				MaxBodyBytes: 1024,
+			},
+		},
+	}
+	resp := &model.ProxyResponse{
+		Header: http.Header{"Content-Type": {"application/json"}, "Content-Length": {"42"}},
+		Body:   http.NoBody,
+	}
+
+	if err := s.transformResponse(http.MethodHead, "/api/v3/search/lucene/", resp); err != nil {
+		t.Fatalf("transformResponse() error = %v", err)
+	}
+	if cl, ok := resp.Header["Content-Length"]; ok {
+		t.Errorf("Content-Length = %q, want it removed for a transformed HEAD response", cl)
+	}
+}